@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CATALOG_EXTENSION_XML  string = ".xml"
+	CATALOG_EXTENSION_YAML string = ".yaml"
+	CATALOG_EXTENSION_YML  string = ".yml"
+	CATALOG_EXTENSION_SQL  string = ".sql"
+)
+
+// CatalogDirectory returns the directory holding the query catalogs:
+// the "xml" folder of the working directory, once the REMOVE_PATHS are stripped from it.
+func CatalogDirectory() (string, error) {
+	// Get the current working directory.
+	path, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	// Remove certain paths from the working directory.
+	for _, e := range REMOVE_PATHS {
+		path = strings.ReplaceAll(path, e, "")
+	}
+
+	return fmt.Sprintf("%s/xml", path), nil
+}
+
+// LoadCatalog reads the catalog of a controller from the catalog directory.
+// The catalog can be written in any of the supported formats, looked up in this order:
+//   - <controller>.xml: the XmlControllers format
+//   - <controller>.yaml or <controller>.yml: the same structure written in YAML
+//   - <controller>/<action>.sql: one file per action, described by a header comment (see LoadSqlCatalog)
+//
+// The function returns an error wrapping fs.ErrNotExist if the controller has no catalog.
+func LoadCatalog(controller string) (*XmlControllers, error) {
+	dir, err := CatalogDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadCatalogPath(filepath.Join(dir, controller))
+}
+
+// LoadCatalogPath reads a catalog from a path without extension, trying every supported format.
+func LoadCatalogPath(path string) (*XmlControllers, error) {
+	for _, ext := range []string{CATALOG_EXTENSION_XML, CATALOG_EXTENSION_YAML, CATALOG_EXTENSION_YML} {
+		if _, err := os.Stat(path + ext); err == nil {
+			return LoadCatalogFile(path + ext)
+		}
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return LoadSqlCatalog(path)
+	}
+
+	return nil, fmt.Errorf("catalog %s: %w", path, fs.ErrNotExist)
+}
+
+// LoadCatalogFile reads an XML or YAML catalog file, choosing the parser from the file extension.
+func LoadCatalogFile(filePath string) (*XmlControllers, error) {
+	result := &XmlControllers{}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case CATALOG_EXTENSION_XML:
+		if err := loadXml(result, filePath); err != nil {
+			return nil, err
+		}
+	case CATALOG_EXTENSION_YAML, CATALOG_EXTENSION_YML:
+		if err := loadYaml(result, filePath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("catalog %s: unsupported format", filePath)
+	}
+
+	return result, nil
+}
+
+// LoadSqlCatalog reads a directory of .sql files, one per action, into a catalog.
+// The action is named after the file, and the controller after the directory.
+// A file named <action>.<dialect>.sql holds the text of the action for that dialect, e.g. GetList.postgres.sql,
+// when the dialect is one of DIALECTS: the other dotted names are action names, e.g. GetList.v2.sql.
+// Leading comment lines may carry the metadata of the action:
+//
+//	-- @controller Base
+//	-- @kind execute
+//	-- @request OrderFilter
+//	-- @response []OrderItem
+//	-- @param from datetime
+//	-- @param name nvarchar required
+//
+// @controller overrides the controller name (e.g. "Base" to share the action),
+// @kind, @request and @response set the attributes of the same name of XmlAction,
+// and each @param declares a placeholder like <param name="..." type="..." required="..."/>.
+// Any other header, or a fourth field of @param other than "required", is an error.
+func LoadSqlCatalog(dir string) (*XmlControllers, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(dir)
+	result := &XmlControllers{XmlNameNode: XmlNameNode{Name: name}}
+	controllers := map[string]*XmlController{}
+	names := []string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), CATALOG_EXTENSION_SQL) {
+			continue
+		}
+
+		controller, action, err := loadSqlAction(filepath.Join(dir, entry.Name()), name)
+		if err != nil {
+			return nil, err
+		}
+
+		contr, ok := controllers[controller]
+		if !ok {
+			contr = &XmlController{XmlNameNode: XmlNameNode{Name: controller}}
+			controllers[controller] = contr
+			names = append(names, controller)
+		}
+		mergeSqlAction(contr, action)
+	}
+
+	sort.Strings(names)
+	for _, e := range names {
+		result.Controllers = append(result.Controllers, *controllers[e])
+	}
+
+	return result, nil
+}
+
+// mergeSqlAction adds an action read from a .sql file to a controller,
+// merging the dialect variants of the same action into one XmlAction.
+func mergeSqlAction(controller *XmlController, action *XmlAction) {
+	for i := range controller.Actions {
+		existing := &controller.Actions[i]
+		if existing.Name != action.Name {
+			continue
+		}
+
+		for _, e := range action.Texts {
+			existing.SetText(e.Dialect, e.Value)
+		}
+
+		existing.Kind = IIF(existing.Kind == "", action.Kind, existing.Kind)
+		existing.Request = IIF(existing.Request == "", action.Request, existing.Request)
+		existing.Response = IIF(existing.Response == "", action.Response, existing.Response)
+
+		for _, e := range action.Params {
+			if Find(existing.Params, func(p XmlParam) bool { return p.Name == e.Name }).Name == "" {
+				existing.Params = append(existing.Params, e)
+			}
+		}
+		return
+	}
+
+	controller.Actions = append(controller.Actions, *action)
+}
+
+// loadYaml reads a YAML file at a given path and unmarshals its contents into an interface.
+func loadYaml(result interface{}, filePath string) error {
+	yamlBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(yamlBytes, result); err != nil {
+		return fmt.Errorf("catalog %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// loadSqlAction reads one .sql file and returns its controller name and action.
+func loadSqlAction(filePath string, controller string) (string, *XmlAction, error) {
+	sqlBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	dialect := DIALECT_DEFAULT
+	if i := strings.LastIndex(name, "."); i > 0 && ComparableContains(strings.ToLower(name[i+1:]), DIALECTS...) {
+		name, dialect = name[:i], strings.ToLower(name[i+1:])
+	}
+
+	action := &XmlAction{XmlNameNode: XmlNameNode{Name: name}}
+	lines := strings.SplitAfter(string(sqlBytes), "\n")
+	header := 0
+
+	for _, e := range lines {
+		line := strings.TrimSpace(e)
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		header += len(e)
+		fields := strings.Fields(strings.TrimPrefix(line, "--"))
+		if len(fields) < 1 || !strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+
+		switch fields[0] {
+		case "@controller":
+			if len(fields) != 2 {
+				return "", nil, fmt.Errorf("catalog %s: @controller expects a name", filePath)
+			}
+			controller = fields[1]
+		case "@kind", "@request", "@response":
+			if len(fields) != 2 {
+				return "", nil, fmt.Errorf("catalog %s: %s expects a value", filePath, fields[0])
+			}
+			switch fields[0] {
+			case "@kind":
+				action.Kind = fields[1]
+			case "@request":
+				action.Request = fields[1]
+			default:
+				action.Response = fields[1]
+			}
+		case "@param":
+			if len(fields) < 2 || len(fields) > 4 {
+				return "", nil, fmt.Errorf("catalog %s: @param expects a name, a type and an optional \"required\"", filePath)
+			}
+			if len(fields) > 3 && fields[3] != "required" {
+				return "", nil, fmt.Errorf("catalog %s: @param %s: unknown option %s, expected \"required\"", filePath, fields[1], fields[3])
+			}
+			param := XmlParam{Name: fields[1], Required: len(fields) > 3}
+			if len(fields) > 2 {
+				param.Type = fields[2]
+			}
+			action.Params = append(action.Params, param)
+		default:
+			return "", nil, fmt.Errorf("catalog %s: unknown header %s", filePath, fields[0])
+		}
+	}
+
+	action.SetText(dialect, string(sqlBytes[header:]))
+	return controller, action, nil
+}
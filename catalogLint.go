@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	CATALOG_ISSUE_MALFORMED            string = "malformed_catalog"
+	CATALOG_ISSUE_DUPLICATE_CONTROLLER string = "duplicate_controller"
+	CATALOG_ISSUE_DUPLICATE_ACTION     string = "duplicate_action"
+	CATALOG_ISSUE_EMPTY_QUERY          string = "empty_query"
+	CATALOG_ISSUE_MISSING_DEFAULT_TEXT string = "missing_default_text"
+	CATALOG_ISSUE_INVALID_TEMPLATE     string = "invalid_template"
+	CATALOG_ISSUE_UNKNOWN_CLAIM        string = "unknown_claim"
+	CATALOG_ISSUE_MISSING_QUERY_PARAMS string = "missing_query_params"
+)
+
+// CATALOG_MODEL_TABLES lists the table variables declared by the [QUERY_PARAMS] script of the Execute family.
+var CATALOG_MODEL_TABLES = []string{"@$Model", "@$Filter", "@$Pagination"}
+
+var catalogQueryParamsPattern = regexp.MustCompile(`\[QUERY_PARAMS\]|\{\{\s*` + TEMPLATE_QUERY_PARAMS + `\s*\}\}`)
+
+// CatalogIssue is a problem found in a catalog by LintCatalogs.
+type CatalogIssue struct {
+	File       string `json:"file"`
+	Controller string `json:"controller,omitempty"`
+	Action     string `json:"action,omitempty"`
+	Dialect    string `json:"dialect,omitempty"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (i CatalogIssue) String() string {
+	location := i.File
+	if i.Controller != "" {
+		location = fmt.Sprintf("%s: %s/%s", location, i.Controller, i.Action)
+	}
+	if i.Dialect != "" {
+		location = fmt.Sprintf("%s (%s)", location, i.Dialect)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, i.Code, i.Message)
+}
+
+// CatalogInventoryItem describes one action of a catalog.
+type CatalogInventoryItem struct {
+	File         string     `json:"file"`
+	Controller   string     `json:"controller"`
+	Action       string     `json:"action"`
+	Dialects     []string   `json:"dialects"`
+	Params       []XmlParam `json:"params,omitempty"`
+	Placeholders []string   `json:"placeholders,omitempty"`
+}
+
+// LoadCatalogs reads every catalog of a directory: the XML and YAML files,
+// and the sub-directories of .sql files. It returns the catalogs keyed by file path,
+// and an issue for each catalog that cannot be read.
+func LoadCatalogs(dir string) (map[string]*XmlControllers, []CatalogIssue, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := map[string]*XmlControllers{}
+	issues := []CatalogIssue{}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+
+		var catalog *XmlControllers
+		if entry.IsDir() {
+			catalog, err = LoadSqlCatalog(path)
+		} else if ComparableContains(ext, CATALOG_EXTENSION_XML, CATALOG_EXTENSION_YAML, CATALOG_EXTENSION_YML) {
+			catalog, err = LoadCatalogFile(path)
+		} else {
+			continue
+		}
+
+		if err != nil {
+			issues = append(issues, CatalogIssue{File: path, Code: CATALOG_ISSUE_MALFORMED, Message: err.Error()})
+			continue
+		}
+
+		result[path] = catalog
+	}
+
+	return result, issues, nil
+}
+
+// LintCatalogs reads every catalog of a directory with LoadCatalogs, and checks each action for:
+//   - duplicate controller and action names
+//   - empty query texts
+//   - actions with dialect variants but no default text, which fail on the DIALECTS without a variant
+//   - query texts that do not compile as a QueryTemplate
+//   - @@ placeholders that are neither registered claims nor SQL_GLOBALS
+//   - query texts using the model tables (@$Model, @$Filter, @$Pagination) without [QUERY_PARAMS]
+//
+// It returns the issues found, and the inventory of all the actions, both sorted by file, controller and action.
+func LintCatalogs(dir string) ([]CatalogIssue, []CatalogInventoryItem, error) {
+	catalogs, issues, err := LoadCatalogs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]string, 0, len(catalogs))
+	for k := range catalogs {
+		files = append(files, k)
+	}
+	sort.Strings(files)
+
+	// Only the first format found by LoadCatalogPath is ever read for a controller.
+	sources := map[string]string{}
+	for _, file := range files {
+		source := strings.TrimSuffix(file, filepath.Ext(file))
+		if other, ok := sources[source]; ok {
+			issues = append(issues, CatalogIssue{File: file, Code: CATALOG_ISSUE_DUPLICATE_CONTROLLER, Message: fmt.Sprintf("catalog is also declared in %s", other)})
+		}
+		sources[source] = file
+	}
+
+	inventory := []CatalogInventoryItem{}
+	for _, file := range files {
+		controllers := map[string]bool{}
+		for _, contr := range catalogs[file].Controllers {
+			if controllers[contr.Name] {
+				issues = append(issues, CatalogIssue{File: file, Controller: contr.Name, Code: CATALOG_ISSUE_DUPLICATE_CONTROLLER, Message: "controller is declared more than once"})
+			}
+			controllers[contr.Name] = true
+
+			actions := map[string]bool{}
+			for i := range contr.Actions {
+				act := &contr.Actions[i]
+				if actions[act.Name] {
+					issues = append(issues, CatalogIssue{File: file, Controller: contr.Name, Action: act.Name, Code: CATALOG_ISSUE_DUPLICATE_ACTION, Message: "action is declared more than once"})
+				}
+				actions[act.Name] = true
+
+				item, actionIssues := lintCatalogAction(file, contr.Name, act)
+				issues = append(issues, actionIssues...)
+				inventory = append(inventory, item)
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].File < issues[j].File
+	})
+
+	return issues, inventory, nil
+}
+
+// lintCatalogAction checks every text of an action, and returns its inventory item.
+func lintCatalogAction(file string, controller string, act *XmlAction) (CatalogInventoryItem, []CatalogIssue) {
+	item := CatalogInventoryItem{File: file, Controller: controller, Action: act.Name, Dialects: []string{}, Params: act.Params}
+	issues := []CatalogIssue{}
+	newIssue := func(dialect string, code string, format string, args ...interface{}) {
+		issues = append(issues, CatalogIssue{File: file, Controller: controller, Action: act.Name, Dialect: dialect, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(act.Texts) < 1 {
+		newIssue(DIALECT_DEFAULT, CATALOG_ISSUE_EMPTY_QUERY, "action has no text")
+	}
+
+	dialects := map[string]bool{}
+	for _, text := range act.Texts {
+		if dialects[text.Dialect] {
+			newIssue(text.Dialect, CATALOG_ISSUE_DUPLICATE_ACTION, "text is declared more than once")
+		}
+		dialects[text.Dialect] = true
+		item.Dialects = append(item.Dialects, text.Dialect)
+
+		if strings.TrimSpace(text.Value) == "" {
+			newIssue(text.Dialect, CATALOG_ISSUE_EMPTY_QUERY, "text is empty")
+			continue
+		}
+
+		template, err := ParseQueryTemplate(text.Value, act.Params...)
+		if err != nil {
+			newIssue(text.Dialect, CATALOG_ISSUE_INVALID_TEMPLATE, "%s", err.Error())
+		} else {
+			if unknowns := template.UnknownClaims(); len(unknowns) > 0 {
+				newIssue(text.Dialect, CATALOG_ISSUE_UNKNOWN_CLAIM, "@@%s: not a registered claim", strings.Join(unknowns, ", @@"))
+			}
+			for _, e := range template.Placeholders() {
+				if !ComparableContains(e, item.Placeholders...) {
+					item.Placeholders = append(item.Placeholders, e)
+				}
+			}
+		}
+
+		if !catalogQueryParamsPattern.MatchString(text.Value) {
+			for _, e := range CATALOG_MODEL_TABLES {
+				if strings.Contains(strings.ToLower(text.Value), strings.ToLower(e)) {
+					newIssue(text.Dialect, CATALOG_ISSUE_MISSING_QUERY_PARAMS, "text uses %s without [QUERY_PARAMS]", e)
+					break
+				}
+			}
+		}
+	}
+
+	if len(act.Texts) > 0 && !dialects[DIALECT_DEFAULT] {
+		missing := Where(DIALECTS, func(e string) bool { return !dialects[e] })
+		if len(missing) > 0 {
+			newIssue(DIALECT_DEFAULT, CATALOG_ISSUE_MISSING_DEFAULT_TEXT, "action has no default text, so it fails on %s", strings.Join(missing, ", "))
+		}
+	}
+
+	return item, issues
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	catalog := `<controllers>
+	<controller name="Orders">
+		<action name="Get"><text>select * from Orders where Id = {{id}}</text><param name="id" type="int"/></action>
+		<action name="Top"><text dialect="postgres">select * from Orders limit 10</text></action>
+		<action name="Region"><text>select * from Orders where RegionID = @@regionID</text></action>
+		<action name="Broken"><text>select {{unknown}}</text></action>
+		<action name="Dialects"><text dialect="sqlserver">select top 10 * from Orders</text><text dialect="postgres">select * from Orders limit 10</text></action>
+	</controller>
+</controllers>`
+	if err := os.WriteFile(filepath.Join(dir, "Orders.xml"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, inventory, err := LintCatalogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := map[string]string{}
+	for _, e := range issues {
+		codes[e.Action] = e.Code
+	}
+	expected := map[string]string{"Top": CATALOG_ISSUE_MISSING_DEFAULT_TEXT, "Region": CATALOG_ISSUE_UNKNOWN_CLAIM, "Broken": CATALOG_ISSUE_INVALID_TEMPLATE}
+	if len(issues) != len(expected) {
+		t.Errorf("issues are %v", issues)
+	}
+	for k, v := range expected {
+		if codes[k] != v {
+			t.Errorf("issue of %s is %q, expected %q", k, codes[k], v)
+		}
+	}
+
+	if len(inventory) != 5 || inventory[0].Action != "Get" || len(inventory[0].Placeholders) != 1 || inventory[0].Placeholders[0] != "id" {
+		t.Errorf("inventory is %+v", inventory)
+	}
+}
+
+func TestXmlActionFindText(t *testing.T) {
+	action := &XmlAction{Texts: []XmlText{{Dialect: DIALECT_POSTGRES, Value: "postgres"}}}
+	action.Name = "Top"
+
+	if text, err := action.FindText(DIALECT_POSTGRES); err != nil || text != "postgres" {
+		t.Errorf("postgres text is %q, %v", text, err)
+	}
+	if _, err := action.FindText(DIALECT_SQLSERVER); !errors.Is(err, ERROR_ACTION_NOT_FOUND) {
+		t.Errorf("sqlserver error is %v", err)
+	}
+
+	action.SetText(DIALECT_DEFAULT, "default")
+	if text, err := action.FindText(DIALECT_SQLSERVER); err != nil || text != "default" || action.Text != "default" {
+		t.Errorf("sqlserver text is %q, %v", text, err)
+	}
+
+	legacy := &XmlAction{Text: "legacy"}
+	if text, err := legacy.FindText(DIALECT_POSTGRES); err != nil || text != "legacy" {
+		t.Errorf("text of a legacy action is %q, %v", text, err)
+	}
+}
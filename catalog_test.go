@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestCatalogFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadCatalogFileYaml(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCatalogFiles(t, dir, map[string]string{"Orders.yaml": `name: Orders
+controllers:
+  - name: Orders
+    actions:
+      - name: GetList
+        kind: execute
+        request: OrderFilter
+        response: "[]OrderItem"
+        params:
+          - name: regionID
+            type: int
+            required: true
+        text: select top 10 * from Orders where RegionID = {{regionID}}
+        dialects:
+          postgres: select * from orders where region_id = {{regionID}} limit 10
+`})
+
+	catalog, err := LoadCatalogPath(filepath.Join(dir, "Orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog.Controllers) != 1 || len(catalog.Controllers[0].Actions) != 1 {
+		t.Fatalf("catalog is %+v", catalog)
+	}
+
+	action := catalog.Controllers[0].Actions[0]
+	if action.Name != "GetList" || action.Kind != ACTION_KIND_EXECUTE || action.Request != "OrderFilter" || action.Response != "[]OrderItem" ||
+		!reflect.DeepEqual(action.Params, []XmlParam{{Name: "regionID", Type: "int", Required: true}}) {
+		t.Errorf("action is %+v", action)
+	}
+	if text, _ := action.FindText(DIALECT_SQLSERVER); text != "select top 10 * from Orders where RegionID = {{regionID}}" {
+		t.Errorf("sqlserver text is %q", text)
+	}
+	if text, _ := action.FindText(DIALECT_POSTGRES); text != "select * from orders where region_id = {{regionID}} limit 10" {
+		t.Errorf("postgres text is %q", text)
+	}
+}
+
+func TestLoadSqlCatalog(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCatalogFiles(t, dir, map[string]string{
+		"Orders/GetList.sql":          "-- @kind execute\n-- @request OrderFilter\n-- @param from datetime\n-- @param name nvarchar required\nselect top 10 * from Orders\n",
+		"Orders/GetList.Postgres.sql": "-- @response []OrderItem\n-- @param regionID int\nselect * from orders limit 10\n",
+		"Orders/GetList.v2.sql":       "select * from OrdersV2\n",
+		"Orders/Ping.sql":             "-- @controller Base\n-- a comment without header\nselect 1\n",
+		"Orders/notes.txt":            "not an action",
+	})
+
+	catalog, err := LoadCatalogPath(filepath.Join(dir, "Orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]XmlAction{}
+	for _, contr := range catalog.Controllers {
+		for _, e := range contr.Actions {
+			actions[contr.Name+"/"+e.Name] = e
+		}
+	}
+	if len(actions) != 3 {
+		t.Fatalf("actions are %+v", actions)
+	}
+
+	getList := actions["Orders/GetList"]
+	if getList.Kind != ACTION_KIND_EXECUTE || getList.Request != "OrderFilter" || getList.Response != "[]OrderItem" || len(getList.Params) != 3 ||
+		Find(getList.Params, func(e XmlParam) bool { return e.Name == "name" }) != (XmlParam{Name: "name", Type: "nvarchar", Required: true}) {
+		t.Errorf("GetList is %+v", getList)
+	}
+	if text, _ := getList.FindText(DIALECT_DEFAULT); text != "select top 10 * from Orders\n" {
+		t.Errorf("default text is %q", text)
+	}
+	if text, _ := getList.FindText(DIALECT_POSTGRES); text != "select * from orders limit 10\n" {
+		t.Errorf("postgres text is %q", text)
+	}
+
+	versioned, ping := actions["Orders/GetList.v2"], actions["Base/Ping"]
+	if text, _ := versioned.FindText(DIALECT_DEFAULT); text != "select * from OrdersV2\n" {
+		t.Errorf("GetList.v2 is %+v", versioned)
+	}
+	if text, _ := ping.FindText(DIALECT_DEFAULT); text != "select 1\n" {
+		t.Errorf("Ping is %+v", ping)
+	}
+}
+
+func TestLoadSqlCatalogRejectsUnknownHeaders(t *testing.T) {
+	tests := map[string]string{
+		"-- @param name nvarchar optional\nselect 1": "unknown option optional",
+		"-- @param\nselect 1":                        "@param expects",
+		"-- @foo bar\nselect 1":                      "unknown header @foo",
+		"-- @kind\nselect 1":                         "@kind expects",
+	}
+
+	for content, expected := range tests {
+		dir := t.TempDir()
+		writeTestCatalogFiles(t, dir, map[string]string{"Orders/GetList.sql": content})
+
+		if _, err := LoadSqlCatalog(filepath.Join(dir, "Orders")); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("error of %q is %v, expected %q", content, err, expected)
+		}
+	}
+}
+
+func TestLoadCatalogFileXmlSetsDefaultText(t *testing.T) {
+	dir := t.TempDir()
+	writeTestCatalogFiles(t, dir, map[string]string{"Orders.xml": `<controllers><controller name="Orders">
+	<action name="GetList"><text dialect="postgres">select * from orders limit 10</text><text>select top 10 * from Orders</text></action>
+</controller></controllers>`})
+
+	catalog, err := LoadCatalogPath(filepath.Join(dir, "Orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action := catalog.Controllers[0].Actions[0]; action.Name != "GetList" || action.Text != "select top 10 * from Orders" || len(action.Texts) != 2 {
+		t.Errorf("action is %+v", action)
+	}
+}
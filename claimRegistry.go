@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ERROR_UNKNOWN_CLAIM is returned when a template is rendered with claims, for an @@ placeholder that is neither a registered claim nor a SQL global.
+var ERROR_UNKNOWN_CLAIM = errors.New("unknown_claim")
+
+// SQL_GLOBALS lists the @@ system functions of the database, which are never claim placeholders.
+// They are compared case-insensitively.
+var SQL_GLOBALS = []string{
+	"CONNECTIONS", "CPU_BUSY", "CURSOR_ROWS", "DATEFIRST", "DBTS", "DEF_SORTORDER_ID", "ERROR", "FETCH_STATUS", "IDENTITY",
+	"IDLE", "IO_BUSY", "LANGID", "LANGUAGE", "LOCK_TIMEOUT", "MAX_CONNECTIONS", "MAX_PRECISION", "MICROSOFTVERSION", "NESTLEVEL",
+	"OPTIONS", "PACKET_ERRORS", "PACK_RECEIVED", "PACK_SENT", "PROCID", "REMSERVER", "ROWCOUNT", "SERVERNAME",
+	"SERVICENAME", "SPID", "TEXTSIZE", "TIMETICKS", "TOTAL_ERRORS", "TOTAL_READ", "TOTAL_WRITE", "TRANCOUNT", "VERSION",
+}
+
+// IClaimRenderer renders the value of an @@ claim placeholder.
+type IClaimRenderer interface {
+	// Render returns the SQL expression replacing the placeholder @@name,
+	// and the statements declaring what the expression uses, run before the query (or an empty string).
+	Render(name string, claims IClaims) (expression string, declaration string, err error)
+}
+
+// ClaimRendererFunc adapts a function to IClaimRenderer.
+type ClaimRendererFunc func(name string, claims IClaims) (string, string, error)
+
+func (f ClaimRendererFunc) Render(name string, claims IClaims) (string, string, error) {
+	return f(name, claims)
+}
+
+var (
+	claimRegistry      = map[string]IClaimRenderer{}
+	claimRegistryMutex sync.RWMutex
+)
+
+func init() {
+	RegisterClaim("userID", ClaimInt(IClaims.GetId))
+	RegisterClaim("clientID", ClaimInt(IClaims.GetClientId))
+	RegisterClaim("unitID", ClaimInt(IClaims.GetUnitId))
+	RegisterClaim("username", ClaimString(IClaims.GetUsername))
+	RegisterClaim("email", ClaimString(IClaims.GetEmail))
+	RegisterClaim("fullName", ClaimString(IClaims.GetFullname))
+	RegisterClaim("phone", ClaimString(IClaims.GetPhone))
+	RegisterClaim("language", ClaimString(IClaims.GetLanguage))
+	RegisterClaim("isAdmin", ClaimBool(IClaims.GetIsAdmin))
+	RegisterClaim("isSystem", ClaimBool(IClaims.GetIsSystem))
+	RegisterClaim("isBaseLanguage", ClaimBool(IClaims.GetIsBaseLanguage))
+}
+
+// RegisterClaim registers the renderer of the @@name placeholder, replacing the existing one.
+// Names are case-sensitive, so @@ROWCOUNT and the other SQL_GLOBALS are never mistaken for a claim.
+//
+// A service adds its own claims at start up, asserting its IClaims implementation in the getter:
+//
+//	utils.RegisterClaim("regionID", utils.ClaimInt(func(c utils.IClaims) int64 { return c.(*Claims).RegionId }))
+func RegisterClaim(name string, renderer IClaimRenderer) {
+	claimRegistryMutex.Lock()
+	defer claimRegistryMutex.Unlock()
+
+	if renderer == nil {
+		delete(claimRegistry, name)
+		return
+	}
+
+	claimRegistry[name] = renderer
+}
+
+// UnregisterClaim removes the renderer of the @@name placeholder.
+func UnregisterClaim(name string) {
+	RegisterClaim(name, nil)
+}
+
+// RegisteredClaims returns the names of all the registered claims, sorted.
+func RegisteredClaims() []string {
+	claimRegistryMutex.RLock()
+	defer claimRegistryMutex.RUnlock()
+
+	result := make([]string, 0, len(claimRegistry))
+	for k := range claimRegistry {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// FindClaimRenderer returns the renderer of the @@name placeholder, or nil if the claim is not registered.
+func FindClaimRenderer(name string) IClaimRenderer {
+	claimRegistryMutex.RLock()
+	defer claimRegistryMutex.RUnlock()
+
+	return claimRegistry[name]
+}
+
+// IsSqlGlobal reports whether @@name is one of the SQL_GLOBALS.
+func IsSqlGlobal(name string) bool {
+	return ComparableContains(strings.ToUpper(name), SQL_GLOBALS...)
+}
+
+const (
+	CLAIM_TYPE_BIGINT   string = "bigint"
+	CLAIM_TYPE_NVARCHAR string = "nvarchar"
+	CLAIM_TYPE_BIT      string = "bit"
+	CLAIM_TYPE_ID_LIST  string = "list"
+)
+
+// IClaimValue is implemented by the renderers whose claim can be passed as a query parameter
+// or a session value instead of being written into the query text, see SetClaimsMode.
+type IClaimValue interface {
+	// Value returns the value of the claim, and its CLAIM_TYPE_*.
+	Value(claims IClaims) (value interface{}, claimType string)
+}
+
+// claimValueRenderer is the renderer of the typed claims built by ClaimInt, ClaimString, ClaimBool and ClaimIdList.
+type claimValueRenderer struct {
+	claimType string
+	value     func(IClaims) interface{}
+}
+
+func (r *claimValueRenderer) Value(claims IClaims) (interface{}, string) {
+	return r.value(claims), r.claimType
+}
+
+func (r *claimValueRenderer) Render(name string, claims IClaims) (string, string, error) {
+	value := r.value(claims)
+
+	switch r.claimType {
+	case CLAIM_TYPE_BIGINT, CLAIM_TYPE_BIT:
+		return fmt.Sprint(value), "", nil
+	case CLAIM_TYPE_NVARCHAR:
+		return fmt.Sprintf("'%s'", Safe(fmt.Sprint(value))), "", nil
+	default:
+		if dialect == DIALECT_POSTGRES {
+			return fmt.Sprintf("(select unnest(cast(array[%s] as bigint[])) as Id) as %s", value, strings.ToLower(SafeColumnName("claim_"+name))), "", nil
+		}
+
+		table := claimTableName(name)
+		builder := &strings.Builder{}
+		builder.WriteString(fmt.Sprintf("declare %s table ([Id] bigint)\n", table))
+		if ids := value.(string); ids != "" {
+			values := Select(strings.Split(ids, ","), func(id string) string { return fmt.Sprintf("(%s)", id) })
+			builder.WriteString(fmt.Sprintf("insert into %s values %s\n", table, strings.Join(values, ",")))
+		}
+		return table, builder.String(), nil
+	}
+}
+
+// ClaimInt renders an integer claim, e.g. @@userID becomes 42.
+func ClaimInt(get func(IClaims) int64) IClaimRenderer {
+	return &claimValueRenderer{claimType: CLAIM_TYPE_BIGINT, value: func(claims IClaims) interface{} {
+		return get(claims)
+	}}
+}
+
+// ClaimString renders a string claim as a quoted literal, e.g. @@username becomes 'john'.
+func ClaimString(get func(IClaims) string) IClaimRenderer {
+	return &claimValueRenderer{claimType: CLAIM_TYPE_NVARCHAR, value: func(claims IClaims) interface{} {
+		return get(claims)
+	}}
+}
+
+// ClaimBool renders a boolean claim as a bit, e.g. @@isAdmin becomes 1.
+func ClaimBool(get func(IClaims) bool) IClaimRenderer {
+	return &claimValueRenderer{claimType: CLAIM_TYPE_BIT, value: func(claims IClaims) interface{} {
+		return BoolToInt(get(claims))
+	}}
+}
+
+// ClaimIdList renders a list of ids as a table variable with a single [Id] column,
+// or as a derived table of an unnested array on DIALECT_POSTGRES,
+// so @@roleIDs can be used as "where RoleID in (select Id from @@roleIDs)" whatever the dialect.
+func ClaimIdList(get func(IClaims) []int64) IClaimRenderer {
+	return &claimValueRenderer{claimType: CLAIM_TYPE_ID_LIST, value: func(claims IClaims) interface{} {
+		ids := Select(get(claims), func(id int64) string { return strconv.FormatInt(id, 10) })
+		return strings.Join(ids, ",")
+	}}
+}
+
+// claimTableName returns the name of the table variable holding a list claim.
+func claimTableName(name string) string {
+	return fmt.Sprintf("@$Claim_%s", SafeColumnName(name))
+}
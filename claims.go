@@ -0,0 +1,72 @@
+package utils
+
+import "time"
+
+// Claims is the IClaims of an authenticated user, as read from a token by JwtVerifier.
+type Claims struct {
+	Id             int64
+	ClientId       int64
+	UnitId         int64
+	Username       string
+	Email          string
+	Fullname       string
+	Phone          string
+	IsAdmin        bool
+	IsSystem       bool
+	Language       string
+	IsBaseLanguage bool
+
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt *time.Time
+	NotBefore *time.Time
+	IssuedAt  *time.Time
+
+	// Values holds every claim of the token, including the ones above, keyed by their name in the token.
+	Values map[string]interface{}
+}
+
+func (c *Claims) GetId() int64 {
+	return c.Id
+}
+
+func (c *Claims) GetClientId() int64 {
+	return c.ClientId
+}
+
+func (c *Claims) GetUnitId() int64 {
+	return c.UnitId
+}
+
+func (c *Claims) GetUsername() string {
+	return c.Username
+}
+
+func (c *Claims) GetEmail() string {
+	return c.Email
+}
+
+func (c *Claims) GetFullname() string {
+	return c.Fullname
+}
+
+func (c *Claims) GetPhone() string {
+	return c.Phone
+}
+
+func (c *Claims) GetIsAdmin() bool {
+	return c.IsAdmin
+}
+
+func (c *Claims) GetIsSystem() bool {
+	return c.IsSystem
+}
+
+func (c *Claims) GetLanguage() string {
+	return c.Language
+}
+
+func (c *Claims) GetIsBaseLanguage() bool {
+	return c.IsBaseLanguage
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// CLAIMS_MODE_TEXT writes the claims into the query text, so each user runs a different query.
+	CLAIMS_MODE_TEXT string = "text"
+	// CLAIMS_MODE_PARAMETERS declares a variable per claim at the start of the batch, set from a query parameter.
+	CLAIMS_MODE_PARAMETERS string = "parameters"
+	// CLAIMS_MODE_SESSION sets every registered claim in the session at the start of the batch,
+	// with sp_set_session_context, so procedures can read them too.
+	CLAIMS_MODE_SESSION string = "session"
+)
+
+var (
+	// ERROR_CLAIMS_MODE_UNSUPPORTED is returned when the claims are rendered for a mode the dialect cannot run.
+	ERROR_CLAIMS_MODE_UNSUPPORTED = errors.New("claims_mode_unsupported")
+
+	claimsMode = CLAIMS_MODE_TEXT
+)
+
+// SetClaimsMode sets how the Execute family passes the claims to the database.
+// With CLAIMS_MODE_PARAMETERS and CLAIMS_MODE_SESSION, the query text is the same for every user and its plan is shared;
+// the claim values are passed as query parameters ("?") before the parameters of the action.
+// Only the claims registered with ClaimInt, ClaimString, ClaimBool and ClaimIdList can be passed this way,
+// the others are always written into the query text.
+// Both modes need statements before the query in the same batch, which Postgres does not run with query parameters,
+// so on DIALECT_POSTGRES rendering claims with them fails with ERROR_CLAIMS_MODE_UNSUPPORTED.
+func SetClaimsMode(mode string) {
+	claimsMode = mode
+}
+
+// GetClaimsMode returns the mode set by SetClaimsMode.
+func GetClaimsMode() string {
+	return claimsMode
+}
+
+// renderedClaims is the rendering of the claims used by a query.
+type renderedClaims struct {
+	prelude     string            // the statements run before the query
+	args        []interface{}     // the parameters of the prelude
+	expressions map[string]string // the SQL written in place of each @@name, keyed by name
+}
+
+// renderClaims renders the given claim placeholders for a mode and the dialect set by SetDialect.
+func renderClaims(names []string, claims IClaims, mode string) (*renderedClaims, error) {
+	result := &renderedClaims{expressions: map[string]string{}}
+	prelude := &strings.Builder{}
+	if dialect == DIALECT_POSTGRES && mode != CLAIMS_MODE_TEXT {
+		return nil, fmt.Errorf("%w: %s on %s", ERROR_CLAIMS_MODE_UNSUPPORTED, mode, dialect)
+	}
+
+	if mode == CLAIMS_MODE_SESSION {
+		result.args = writeSessionPrelude(prelude, claims)
+	}
+
+	for _, name := range names {
+		renderer := FindClaimRenderer(name)
+		if renderer == nil {
+			return nil, fmt.Errorf("@@%s: %w", name, ERROR_UNKNOWN_CLAIM)
+		}
+
+		valuer, typed := renderer.(IClaimValue)
+		if mode == CLAIMS_MODE_TEXT || !typed {
+			expression, declaration, err := renderer.Render(name, claims)
+			if err != nil {
+				return nil, fmt.Errorf("@@%s: %w", name, err)
+			}
+
+			prelude.WriteString(declaration)
+			result.expressions[name] = expression
+			continue
+		}
+
+		value, claimType := valuer.Value(claims)
+		source := fmt.Sprintf("session_context(N'%s')", Safe(name))
+
+		switch {
+		case mode == CLAIMS_MODE_PARAMETERS && claimType == CLAIM_TYPE_ID_LIST:
+			prelude.WriteString(declareClaimList(name, "?"))
+			result.args = append(result.args, value)
+			result.expressions[name] = claimTableName(name)
+		case mode == CLAIMS_MODE_PARAMETERS:
+			variable := claimVariableName(name)
+			prelude.WriteString(fmt.Sprintf("declare %s %s = ?\n", variable, claimSqlType(claimType)))
+			result.args = append(result.args, value)
+			result.expressions[name] = variable
+		case claimType == CLAIM_TYPE_ID_LIST:
+			prelude.WriteString(declareClaimList(name, fmt.Sprintf("cast(%s as nvarchar(max))", source)))
+			result.expressions[name] = claimTableName(name)
+		default:
+			result.expressions[name] = fmt.Sprintf("cast(%s as %s)", source, claimSqlType(claimType))
+		}
+	}
+
+	result.prelude = prelude.String()
+	return result, nil
+}
+
+// writeSessionPrelude writes the statements setting every registered typed claim in the session,
+// and returns their parameters.
+func writeSessionPrelude(builder *strings.Builder, claims IClaims) []interface{} {
+	args := []interface{}{}
+
+	for _, name := range RegisteredClaims() {
+		valuer, ok := FindClaimRenderer(name).(IClaimValue)
+		if !ok {
+			continue
+		}
+
+		value, _ := valuer.Value(claims)
+		builder.WriteString(fmt.Sprintf("exec sp_set_session_context @key = N'%s', @value = ?\n", Safe(name)))
+		args = append(args, value)
+	}
+
+	return args
+}
+
+// declareClaimList returns the declaration of the table variable of a list claim, filled from a comma separated source.
+func declareClaimList(name string, source string) string {
+	table := claimTableName(name)
+	return fmt.Sprintf("declare %s table ([Id] bigint)\ninsert into %s select cast(value as bigint) from string_split(%s, ',') where value <> ''\n", table, table, source)
+}
+
+// claimVariableName returns the name of the variable holding a scalar claim.
+func claimVariableName(name string) string {
+	return fmt.Sprintf("@$Claim_%s", SafeColumnName(name))
+}
+
+// claimSqlType returns the SQL type a scalar claim is read as.
+func claimSqlType(claimType string) string {
+	switch claimType {
+	case CLAIM_TYPE_BIGINT:
+		return "bigint"
+	case CLAIM_TYPE_BIT:
+		return "int"
+	default:
+		return "nvarchar(max)"
+	}
+}
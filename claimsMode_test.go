@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderWithClaimsModeDialects(t *testing.T) {
+	RegisterClaim("roleIDs", ClaimIdList(func(c IClaims) []int64 { return c.(*Claims).Values["roleIDs"].([]int64) }))
+	t.Cleanup(func() {
+		UnregisterClaim("roleIDs")
+		SetDialect(DIALECT_DEFAULT)
+	})
+
+	template, err := ParseQueryTemplate("select * from Orders where UserID = @@userID and Username = @@username and IsAdmin = @@isAdmin" +
+		" and RoleID in (select Id from @@roleIDs)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &Claims{Id: 42, Username: "o'neil", IsAdmin: true, Values: map[string]interface{}{"roleIDs": []int64{1, 2}}}
+
+	tests := []struct {
+		mode     string
+		dialect  string
+		contains []string
+		excludes []string
+		err      error
+	}{
+		{CLAIMS_MODE_TEXT, DIALECT_DEFAULT, []string{"declare @$Claim_roleIDs table", "insert into @$Claim_roleIDs values (1),(2)", "UserID = 42", "Username = 'o''neil'"}, nil, nil},
+		{CLAIMS_MODE_TEXT, DIALECT_SQLSERVER, []string{"declare @$Claim_roleIDs table", "UserID = 42", "IsAdmin = 1"}, nil, nil},
+		{CLAIMS_MODE_TEXT, DIALECT_POSTGRES, []string{"(select unnest(cast(array[1,2] as bigint[])) as Id) as claim_roleids", "UserID = 42"}, []string{"declare", "@$"}, nil},
+		{CLAIMS_MODE_PARAMETERS, DIALECT_DEFAULT, []string{"declare @$Claim_userID bigint = ?", "string_split(?, ',')", "UserID = @$Claim_userID"}, []string{"42"}, nil},
+		{CLAIMS_MODE_PARAMETERS, DIALECT_SQLSERVER, []string{"declare @$Claim_username nvarchar(max) = ?", "Username = @$Claim_username"}, []string{"o''neil"}, nil},
+		{CLAIMS_MODE_PARAMETERS, DIALECT_POSTGRES, nil, nil, ERROR_CLAIMS_MODE_UNSUPPORTED},
+		{CLAIMS_MODE_SESSION, DIALECT_DEFAULT, []string{"exec sp_set_session_context @key = N'userID', @value = ?", "UserID = cast(session_context(N'userID') as bigint)"}, nil, nil},
+		{CLAIMS_MODE_SESSION, DIALECT_SQLSERVER, []string{"exec sp_set_session_context @key = N'roleIDs', @value = ?", "select Id from @$Claim_roleIDs"}, nil, nil},
+		{CLAIMS_MODE_SESSION, DIALECT_POSTGRES, nil, nil, ERROR_CLAIMS_MODE_UNSUPPORTED},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode+"/"+IIF(test.dialect == "", "default", test.dialect), func(t *testing.T) {
+			SetDialect(test.dialect)
+
+			query, args, err := template.RenderWithClaimsMode(TemplateValues{}, claims, test.mode)
+			if test.err != nil || err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error is %v, expected %v", err, test.err)
+				}
+				return
+			}
+
+			if count := strings.Count(query, "?"); count != len(args) {
+				t.Errorf("query has %d parameters, but %d values\n%s", count, len(args), query)
+			}
+			for _, e := range test.contains {
+				if !strings.Contains(query, e) {
+					t.Errorf("query does not contain %q\n%s", e, query)
+				}
+			}
+			for _, e := range test.excludes {
+				if strings.Contains(query, e) {
+					t.Errorf("query contains %q\n%s", e, query)
+				}
+			}
+		})
+	}
+}
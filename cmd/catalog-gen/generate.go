@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	utils "github.com/midea-media-llc/mm-go-utilities"
+)
+
+const untypedParameter = "interface{}"
+
+// generatedAction is an action reachable through FindQuery, with the Go names of its wrapper.
+type generatedAction struct {
+	controller string
+	action     *utils.XmlAction
+	identifier string
+	constant   string
+}
+
+// catalogPrecedence lists the extensions of the catalogs in the order LoadCatalogPath looks them up,
+// the directory of .sql files having no extension.
+var catalogPrecedence = []string{utils.CATALOG_EXTENSION_XML, utils.CATALOG_EXTENSION_YAML, utils.CATALOG_EXTENSION_YML, ""}
+
+// generate returns the formatted source of the wrappers of all the actions of the catalogs.
+// The controller of an action is the name of its catalog, as FindQuery expects it,
+// so only the actions of the controller named after the catalog and of the "Base" controller are generated.
+// When a controller has catalogs in several formats, only the one read by FindQuery is generated, see LoadCatalogPath.
+func generate(catalogs map[string]*utils.XmlControllers, pkg string, imports []string) ([]byte, error) {
+	files := map[string]string{}
+	for k := range catalogs {
+		ext := strings.ToLower(filepath.Ext(k))
+		if !utils.ComparableContains(ext, catalogPrecedence...) {
+			ext = ""
+		}
+
+		controller := strings.TrimSuffix(filepath.Base(k), filepath.Ext(k))
+		if ext == "" {
+			controller = filepath.Base(k)
+		}
+
+		if other, ok := files[controller]; !ok || catalogRank(k) < catalogRank(other) {
+			files[controller] = k
+		}
+	}
+
+	controllers := make([]string, 0, len(files))
+	for k := range files {
+		controllers = append(controllers, k)
+	}
+	sort.Strings(controllers)
+
+	actions := []generatedAction{}
+	identifiers := map[string]string{}
+
+	for _, controller := range controllers {
+		file := files[controller]
+
+		for _, contr := range catalogs[file].Controllers {
+			if contr.Name != controller && contr.Name != "Base" {
+				continue
+			}
+
+			for i := range contr.Actions {
+				act := &contr.Actions[i]
+				identifier := goIdentifier(controller) + goIdentifier(act.Name)
+				if other, ok := identifiers[identifier]; ok {
+					return nil, fmt.Errorf("%s/%s: wrapper %s is already generated for %s", controller, act.Name, identifier, other)
+				}
+				identifiers[identifier] = controller + "/" + act.Name
+
+				actions = append(actions, generatedAction{
+					controller: controller,
+					action:     act,
+					identifier: identifier,
+					constant:   "Action" + identifier,
+				})
+			}
+		}
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("// Code generated by catalog-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(builder, "package %s\n\n", pkg)
+	builder.WriteString("import (\n")
+	fmt.Fprintf(builder, "\tutils %q\n", "github.com/midea-media-llc/mm-go-utilities")
+	for _, e := range imports {
+		if e = strings.TrimSpace(e); e != "" {
+			fmt.Fprintf(builder, "\t%q\n", e)
+		}
+	}
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("const (\n")
+	for _, e := range controllers {
+		fmt.Fprintf(builder, "\tController%s = %q\n", goIdentifier(e), e)
+	}
+	for _, e := range actions {
+		fmt.Fprintf(builder, "\t%s = %q\n", e.constant, e.action.Name)
+	}
+	builder.WriteString(")\n")
+
+	for _, e := range actions {
+		if err := generateAction(builder, e); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := format.Source([]byte(builder.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated code is not valid Go: %w", err)
+	}
+
+	return result, nil
+}
+
+// catalogRank returns the index of the format of a catalog in catalogPrecedence.
+func catalogRank(path string) int {
+	ext := strings.ToLower(filepath.Ext(path))
+	for i, e := range catalogPrecedence {
+		if e == ext {
+			return i
+		}
+	}
+	return len(catalogPrecedence) - 1
+}
+
+// generateAction writes the wrapper of one action, calling the Execute function matching its kind.
+func generateAction(builder *strings.Builder, e generatedAction) error {
+	requests := splitTypes(e.action.Request)
+	responses := splitTypes(e.action.Response)
+	controller := "Controller" + goIdentifier(e.controller)
+
+	typeParams := "R, T any"
+	params := []string{"db utils.IGormDB[R, T]", "claims utils.IClaims"}
+	args := []string{"db", controller, e.constant, "claims"}
+	function := ""
+
+	switch e.action.Kind {
+	case "", utils.ACTION_KIND_EXECUTE:
+		function = "Execute"
+		params = append(params, "request "+typeAt(requests, 0), "result "+resultType(responses, 0))
+		args = append(args, "request", "result")
+	case utils.ACTION_KIND_EXECUTE_ID:
+		function = "ExecuteId"
+		typeParams = "R utils.ISqlRow, T any"
+		params = append(params, "id "+typeAt(requests, 0), "result "+resultType(responses, 0))
+		args = append(args, "id", "result")
+	case utils.ACTION_KIND_MULTIPLE_RESULT:
+		function = "ExecuteMultipleResult"
+		params = append(params, "request "+typeAt(requests, 0))
+		args = append(args, "request")
+	case utils.ACTION_KIND_ID_MULTIPLE_RESULT:
+		function = "ExecuteIdMultipleResult"
+		params = append(params, "id "+typeAt(requests, 0))
+		args = append(args, "id")
+	case utils.ACTION_KIND_FILTER_PAGINATION:
+		function = "FilterPagination"
+		params = append(params, "filters "+typeAt(requests, 0), "paging "+typeAt(requests, 1))
+		args = append(args, "filters", "paging")
+	default:
+		return fmt.Errorf("%s/%s: unknown kind %q", e.controller, e.action.Name, e.action.Kind)
+	}
+
+	if function != "Execute" && function != "ExecuteId" {
+		if len(responses) < 1 {
+			params = append(params, "results ..."+untypedParameter)
+			args = append(args, "results...")
+		}
+		for i := range responses {
+			params = append(params, fmt.Sprintf("result%d %s", i+1, resultType(responses, i)))
+			args = append(args, fmt.Sprintf("result%d", i+1))
+		}
+	}
+
+	fmt.Fprintf(builder, "\n// %s runs the %s/%s action with utils.%s.\n", e.identifier, e.controller, e.action.Name, function)
+	fmt.Fprintf(builder, "func %s[%s](%s) error {\n", e.identifier, typeParams, strings.Join(params, ", "))
+	fmt.Fprintf(builder, "\treturn utils.%s(%s)\n", function, strings.Join(args, ", "))
+	builder.WriteString("}\n")
+	return nil
+}
+
+// splitTypes splits a comma separated list of types, e.g. "[]OrderItem, *Total".
+func splitTypes(value string) []string {
+	result := []string{}
+	for _, e := range strings.Split(value, utils.ACTION_TYPE_SEPARATOR) {
+		if e = strings.TrimSpace(e); e != "" {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// typeAt returns the type declared at the given index, or interface{} when none is declared.
+func typeAt(types []string, index int) string {
+	if index < len(types) {
+		return types[index]
+	}
+	return untypedParameter
+}
+
+// resultType returns the pointer to the result type declared at the given index, or interface{} when none is declared.
+func resultType(types []string, index int) string {
+	if index < len(types) {
+		return "*" + types[index]
+	}
+	return untypedParameter
+}
+
+// goIdentifier turns a catalog name, e.g. "order-item" or "get_list", into an exported Go identifier.
+func goIdentifier(name string) string {
+	builder := &strings.Builder{}
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+
+	result := builder.String()
+	if result == "" || unicode.IsDigit([]rune(result)[0]) {
+		result = "X" + result
+	}
+	return result
+}
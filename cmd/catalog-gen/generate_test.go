@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	utils "github.com/midea-media-llc/mm-go-utilities"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// testdata/catalog holds the Orders controller both as Orders.xml and as the Orders directory:
+// only Orders.xml, read by FindQuery, is generated.
+func TestGenerateGolden(t *testing.T) {
+	catalogs, issues, err := utils.LoadCatalogs(filepath.Join("testdata", "catalog"))
+	if err != nil || len(issues) > 0 {
+		t.Fatalf("catalogs cannot be read: %v %v", issues, err)
+	}
+
+	code, err := generate(catalogs, "queries", []string{"github.com/acme/orders/models", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "catalog.gen.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(code) != string(expected) {
+		t.Errorf("generated code differs from %s, run go test -update to see the difference:\n%s", golden, code)
+	}
+}
+
+func TestGenerateRejectsDuplicateWrappers(t *testing.T) {
+	catalogs := map[string]*utils.XmlControllers{
+		"Orders.xml": {Controllers: []utils.XmlController{{
+			XmlNameNode: utils.XmlNameNode{Name: "Orders"},
+			Actions:     []utils.XmlAction{{XmlNameNode: utils.XmlNameNode{Name: "get-list"}}, {XmlNameNode: utils.XmlNameNode{Name: "GetList"}}},
+		}}},
+	}
+
+	if _, err := generate(catalogs, "queries", nil); err == nil || !strings.Contains(err.Error(), "OrdersGetList") {
+		t.Errorf("error is %v", err)
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{"order-item": "OrderItem", "get_list": "GetList", "2fa": "X2fa", "": "X"} {
+		if result := goIdentifier(name); result != expected {
+			t.Errorf("identifier of %q is %q, expected %q", name, result, expected)
+		}
+	}
+}
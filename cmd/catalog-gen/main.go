@@ -0,0 +1,71 @@
+// Command catalog-gen generates typed Go wrappers for the actions of the query catalogs.
+//
+// For each action, it emits the constants of its controller and action names, and a function calling
+// the Execute function named by the kind of the action with the request and response types declared in the catalog:
+//
+//	<action name="GetList" kind="execute" request="*models.OrderFilter" response="[]models.OrderItem">
+//
+// generates
+//
+//	func OrderGetList[R, T any](db utils.IGormDB[R, T], claims utils.IClaims, request *models.OrderFilter, result *[]models.OrderItem) error
+//
+// It is meant to be run by go:generate, next to the package holding the generated file:
+//
+//	//go:generate go run github.com/midea-media-llc/mm-go-utilities/cmd/catalog-gen -dir ../xml -package queries -import github.com/acme/orders/models -out catalog.gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	utils "github.com/midea-media-llc/mm-go-utilities"
+)
+
+func main() {
+	dir := flag.String("dir", "", "the catalog directory (default: the xml directory used by FindQuery)")
+	pkg := flag.String("package", "queries", "the package of the generated file")
+	out := flag.String("out", "", "the generated file (default: the standard output)")
+	imports := flag.String("import", "", "the packages of the request and response types, comma separated")
+	flag.Parse()
+
+	if *dir == "" {
+		path, err := utils.CatalogDirectory()
+		if err != nil {
+			exit(err)
+		}
+		*dir = path
+	}
+
+	catalogs, issues, err := utils.LoadCatalogs(*dir)
+	if err != nil {
+		exit(err)
+	}
+
+	if len(issues) > 0 {
+		for _, e := range issues {
+			fmt.Fprintln(os.Stderr, e.String())
+		}
+		exit(fmt.Errorf("%d catalog(s) cannot be read in %s", len(issues), *dir))
+	}
+
+	code, err := generate(catalogs, *pkg, strings.Split(*imports, ","))
+	if err != nil {
+		exit(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(code)
+		return
+	}
+
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
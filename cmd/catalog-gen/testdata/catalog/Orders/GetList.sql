@@ -0,0 +1,2 @@
+-- @kind execute
+select * from Orders
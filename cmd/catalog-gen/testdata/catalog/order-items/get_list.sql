@@ -0,0 +1,4 @@
+-- @kind execute
+-- @request *models.OrderItemFilter
+-- @response []models.OrderItem
+select * from OrderItems
@@ -0,0 +1,66 @@
+// Command catalog-lint checks the query catalogs of a service.
+//
+// It reads every catalog of the catalog directory with the same parsers as FindQuery,
+// prints the issues found, and exits with a non-zero status if there is any:
+//
+//	go run github.com/midea-media-llc/mm-go-utilities/cmd/catalog-lint -dir ./xml -claims regionID,roleIDs
+//
+// With -json, it also prints the inventory of all the actions as JSON on the standard output.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	utils "github.com/midea-media-llc/mm-go-utilities"
+)
+
+func main() {
+	dir := flag.String("dir", "", "the catalog directory (default: the xml directory used by FindQuery)")
+	inventory := flag.Bool("json", false, "print the inventory of all the actions as JSON")
+	claims := flag.String("claims", "", "the @@ claims registered by the service on top of the built-in ones, comma separated")
+	flag.Parse()
+
+	// The renderers of the service are unknown here: the claims only need to be known to the parser.
+	for _, e := range strings.Split(*claims, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			utils.RegisterClaim(e, utils.ClaimString(func(utils.IClaims) string { return "" }))
+		}
+	}
+
+	if *dir == "" {
+		path, err := utils.CatalogDirectory()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		*dir = path
+	}
+
+	issues, items, err := utils.LintCatalogs(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, e := range issues {
+		fmt.Fprintln(os.Stderr, e.String())
+	}
+
+	if *inventory {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(items); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "%d issue(s) found in %s\n", len(issues), *dir)
+		os.Exit(1)
+	}
+}
@@ -17,6 +17,8 @@ var TYPE_TIMESTAMP reflect.Type = nil
 var TYPE_TIMESTAMP_POINTER reflect.Type = nil
 var TYPE_GUID reflect.Type = nil
 var TYPE_GUID_POINTER reflect.Type = nil
+
+// TYPE_SQL_ERROR is kept for compatibility, HandleSqlError now detects the SQL errors with ExtractSqlError.
 var TYPE_SQL_ERROR reflect.Type = nil
 
 func SetType(time reflect.Type, timePtr reflect.Type, stamp reflect.Type, stampPtr reflect.Type, sqlError reflect.Type, uuid reflect.Type, uuidPtr reflect.Type) {
@@ -0,0 +1,23 @@
+package utils
+
+const (
+	DIALECT_DEFAULT   string = ""
+	DIALECT_SQLSERVER string = "sqlserver"
+	DIALECT_POSTGRES  string = "postgres"
+)
+
+// DIALECTS lists the dialects of the catalogs besides DIALECT_DEFAULT, e.g. the suffixes of the files <action>.<dialect>.sql.
+var DIALECTS = []string{DIALECT_SQLSERVER, DIALECT_POSTGRES}
+
+var dialect = DIALECT_DEFAULT
+
+// SetDialect sets the database engine the queries are run against.
+// Catalog actions use the text written for this dialect, or their default text when there is none.
+func SetDialect(value string) {
+	dialect = value
+}
+
+// GetDialect returns the database engine set by SetDialect.
+func GetDialect() string {
+	return dialect
+}
@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	ERROR_SEVERITY_INFO     string = "info"
+	ERROR_SEVERITY_WARNING  string = "warning"
+	ERROR_SEVERITY_ERROR    string = "error"
+	ERROR_SEVERITY_CRITICAL string = "critical"
+
+	// ERROR_LANGUAGE_DEFAULT is the language of the messages used when the claims have no language,
+	// or when a code has no message in their language.
+	ERROR_LANGUAGE_DEFAULT string = "en"
+
+	ERROR_CODE_SQL     string = "sql_error"
+	ERROR_CODE_UNKNOWN string = "unknown"
+
+	// ERROR_PARAM_MESSAGE is the param holding the original message of an error, e.g. the SQL message of a sql_user_error.
+	ERROR_PARAM_MESSAGE string = "message"
+)
+
+// ErrorCode is an application error code of the catalog.
+// Messages are templates keyed by language, whose {name} placeholders are replaced by the params of the error.
+// Err is the sentinel error of the code, if any, matched with errors.Is.
+type ErrorCode struct {
+	Code       string
+	Err        error
+	HttpStatus int
+	GrpcCode   codes.Code
+	Severity   string
+	Messages   map[string]string
+}
+
+// Message renders the message template of the given language, e.g. "vi" or "vi-VN",
+// falling back to ERROR_LANGUAGE_DEFAULT, then to the code itself.
+func (c *ErrorCode) Message(language string, params map[string]string) string {
+	template, ok := c.findMessage(language)
+	if !ok {
+		return c.Code
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func (c *ErrorCode) findMessage(language string) (string, bool) {
+	language = strings.ToLower(language)
+	base, _, _ := strings.Cut(language, "-")
+
+	for _, e := range []string{language, base, ERROR_LANGUAGE_DEFAULT} {
+		if result, ok := c.Messages[e]; ok && e != "" {
+			return result, true
+		}
+	}
+	return "", false
+}
+
+var (
+	errorCatalog = map[string]*ErrorCode{}
+	// errorCatalogOrder lists the registered codes in registration order, in which FindErrorCodeOf matches their Err.
+	errorCatalogOrder []string
+	errorCatalogMutex sync.RWMutex
+)
+
+func init() {
+	RegisterErrorCode(&ErrorCode{Code: VALIDATE_ERROR_CODE, HttpStatus: http.StatusBadRequest, GrpcCode: codes.InvalidArgument,
+		Severity: ERROR_SEVERITY_WARNING, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The request is invalid."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_ACTION_NOT_FOUND.Error(), Err: ERROR_ACTION_NOT_FOUND, HttpStatus: http.StatusNotImplemented, GrpcCode: codes.Unimplemented,
+		Severity: ERROR_SEVERITY_ERROR, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The action is not implemented."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_UNKNOWN_CLAIM.Error(), Err: ERROR_UNKNOWN_CLAIM, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Internal,
+		Severity: ERROR_SEVERITY_CRITICAL, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The query uses an unknown claim."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_CLAIMS_MODE_UNSUPPORTED.Error(), Err: ERROR_CLAIMS_MODE_UNSUPPORTED, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Internal,
+		Severity: ERROR_SEVERITY_CRITICAL, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The claims mode is not supported by the database."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_EVENT_TYPE_MISMATCH.Error(), Err: ERROR_EVENT_TYPE_MISMATCH, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Internal,
+		Severity: ERROR_SEVERITY_CRITICAL, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The event does not have the type of its topic."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_EVENT_TOPIC_INVALID.Error(), Err: ERROR_EVENT_TOPIC_INVALID, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Internal,
+		Severity: ERROR_SEVERITY_CRITICAL, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The event is published on a topic pattern."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_CODE_SQL, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Internal,
+		Severity: ERROR_SEVERITY_ERROR, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "A database error occurred."}})
+	RegisterErrorCode(&ErrorCode{Code: ERROR_CODE_UNKNOWN, HttpStatus: http.StatusInternalServerError, GrpcCode: codes.Unknown,
+		Severity: ERROR_SEVERITY_ERROR, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "An unexpected error occurred."}})
+
+	for _, e := range []error{ERROR_JWT_MALFORMED, ERROR_JWT_ALGORITHM, ERROR_JWT_KEY_NOT_FOUND, ERROR_JWT_SIGNATURE,
+		ERROR_JWT_EXPIRED, ERROR_JWT_NOT_YET_VALID, ERROR_JWT_ISSUER, ERROR_JWT_AUDIENCE} {
+		RegisterErrorCode(&ErrorCode{Code: e.Error(), Err: e, HttpStatus: http.StatusUnauthorized, GrpcCode: codes.Unauthenticated,
+			Severity: ERROR_SEVERITY_WARNING, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The access token is invalid."}})
+	}
+	SetErrorMessage(ERROR_JWT_EXPIRED.Error(), ERROR_LANGUAGE_DEFAULT, "The access token has expired.")
+}
+
+// RegisterErrorCode registers an error code, replacing the existing one, which keeps its place in the registration order.
+// A service registers its own codes, and the messages of its languages, at start up:
+//
+//	utils.RegisterErrorCode(&utils.ErrorCode{Code: "order_closed", HttpStatus: 409, GrpcCode: codes.FailedPrecondition,
+//		Severity: utils.ERROR_SEVERITY_WARNING, Messages: map[string]string{"en": "The order {id} is closed."}})
+func RegisterErrorCode(code *ErrorCode) {
+	errorCatalogMutex.Lock()
+	defer errorCatalogMutex.Unlock()
+
+	if _, ok := errorCatalog[code.Code]; !ok {
+		errorCatalogOrder = append(errorCatalogOrder, code.Code)
+	}
+	errorCatalog[code.Code] = code
+}
+
+// UnregisterErrorCode removes a registered code.
+func UnregisterErrorCode(code string) {
+	errorCatalogMutex.Lock()
+	defer errorCatalogMutex.Unlock()
+
+	if _, ok := errorCatalog[code]; !ok {
+		return
+	}
+
+	delete(errorCatalog, code)
+	errorCatalogOrder = Where(errorCatalogOrder, func(e string) bool { return e != code })
+}
+
+// SetErrorMessage sets the message template of a registered code for the given language.
+// It does nothing if the code is not registered.
+func SetErrorMessage(code string, language string, template string) {
+	errorCatalogMutex.Lock()
+	defer errorCatalogMutex.Unlock()
+
+	e, ok := errorCatalog[code]
+	if !ok {
+		return
+	}
+
+	// Replace the code by a copy, so the codes already returned by FindErrorCode are never modified.
+	result := *e
+	result.Messages = make(map[string]string, len(e.Messages)+1)
+	for k, v := range e.Messages {
+		result.Messages[k] = v
+	}
+	result.Messages[strings.ToLower(language)] = template
+	errorCatalog[code] = &result
+}
+
+// RegisteredErrorCodes returns the registered codes, sorted.
+func RegisteredErrorCodes() []string {
+	errorCatalogMutex.RLock()
+	defer errorCatalogMutex.RUnlock()
+
+	result := make([]string, 0, len(errorCatalog))
+	for k := range errorCatalog {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// FindErrorCode returns the registered code, or nil.
+func FindErrorCode(code string) *ErrorCode {
+	errorCatalogMutex.RLock()
+	defer errorCatalogMutex.RUnlock()
+
+	return errorCatalog[code]
+}
+
+// FindErrorCodeOf returns the registered code of an error:
+// the code of an ICodedError or of an IValidateError, the code whose Err matches the error,
+// or the code equal to the error message. It returns nil if the error has no registered code.
+// When several codes match, the Err closest to the error in its chain wins, then the code registered first.
+func FindErrorCodeOf(err error) *ErrorCode {
+	if err == nil {
+		return nil
+	}
+
+	var coded ICodedError
+	if errors.As(err, &coded) {
+		if result := FindErrorCode(coded.ErrorCode()); result != nil {
+			return result
+		}
+	}
+
+	var validate IValidateError
+	if errors.As(err, &validate) {
+		return FindErrorCode(validate.Error())
+	}
+
+	errorCatalogMutex.RLock()
+	defer errorCatalogMutex.RUnlock()
+
+	var result *ErrorCode
+	findError(err, func(cause error) bool {
+		for _, e := range errorCatalogOrder {
+			if code := errorCatalog[e]; code.Err != nil && isError(cause, code.Err) {
+				result = code
+				return true
+			}
+		}
+		return false
+	})
+	if result != nil {
+		return result
+	}
+	return errorCatalog[err.Error()]
+}
+
+// isError reports whether an error of a chain is the target, as errors.Is does without unwrapping it.
+func isError(err error, target error) bool {
+	if reflect.TypeOf(err).Comparable() && err == target {
+		return true
+	}
+	if e, ok := err.(interface{ Is(error) bool }); ok {
+		return e.Is(target)
+	}
+	return false
+}
+
+// ICodedError is an error with a code of the catalog, and the params of its message.
+type ICodedError interface {
+	error
+	ErrorCode() string
+	ErrorParams() map[string]string
+}
+
+type codedError struct {
+	code    string
+	message string
+	params  map[string]string
+	err     error
+}
+
+// NewCodedError returns an ICodedError, whose message is the message of the code in ERROR_LANGUAGE_DEFAULT.
+func NewCodedError(code string, params map[string]string) error {
+	message := code
+	if e := FindErrorCode(code); e != nil {
+		message = e.Message(ERROR_LANGUAGE_DEFAULT, params)
+	}
+	return &codedError{code: code, message: message, params: params}
+}
+
+func (s *codedError) Error() string {
+	return s.message
+}
+
+func (s *codedError) Unwrap() error {
+	return s.err
+}
+
+func (s *codedError) Format(f fmt.State, verb rune) {
+	formatError(f, verb, s)
+}
+
+func (s *codedError) ErrorCode() string {
+	return s.code
+}
+
+func (s *codedError) ErrorParams() map[string]string {
+	return s.params
+}
+
+// LocalizeError returns the message of an error in the language of the claims,
+// or the error message if the error has no registered code. Claims may be nil.
+func LocalizeError(err error, claims IClaims) string {
+	language := ""
+	if claims != nil {
+		language = claims.GetLanguage()
+	}
+	return LocalizeErrorLanguage(err, language)
+}
+
+// LocalizeErrorLanguage returns the message of an error in the given language,
+// or the error message if the error has no registered code.
+func LocalizeErrorLanguage(err error, language string) string {
+	if err == nil {
+		return ""
+	}
+
+	code := FindErrorCodeOf(err)
+	if code == nil {
+		return err.Error()
+	}
+
+	return code.Message(language, errorParams(err))
+}
+
+// unknownErrorMessage returns the message of ERROR_CODE_UNKNOWN in the given language, sent instead of the message of an unknown error.
+func unknownErrorMessage(language string) string {
+	if code := FindErrorCode(ERROR_CODE_UNKNOWN); code != nil {
+		return code.Message(language, nil)
+	}
+	return ERROR_CODE_UNKNOWN
+}
+
+// sqlErrorMessage returns the message of ERROR_CODE_SQL in the given language, sent instead of the message of an unclassified SQL error.
+func sqlErrorMessage(language string) string {
+	if code := FindErrorCode(ERROR_CODE_SQL); code != nil {
+		return code.Message(language, nil)
+	}
+	return ERROR_CODE_SQL
+}
+
+// errorParams returns the params of the message of an error: the original message, and the params of an ICodedError.
+func errorParams(err error) map[string]string {
+	result := map[string]string{ERROR_PARAM_MESSAGE: err.Error()}
+
+	var coded ICodedError
+	if errors.As(err, &coded) {
+		for k, v := range coded.ErrorParams() {
+			result[k] = v
+		}
+	}
+	return result
+}
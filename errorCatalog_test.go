@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func registerTestErrorCodes(t *testing.T, codes ...*ErrorCode) {
+	for _, e := range codes {
+		RegisterErrorCode(e)
+		code := e.Code
+		t.Cleanup(func() { UnregisterErrorCode(code) })
+	}
+}
+
+func TestFindErrorCodeOfPrefersClosestError(t *testing.T) {
+	errStore := errors.New("test_store_failed")
+	errOrderStore := fmt.Errorf("test_order_store_failed: %w", errStore)
+	registerTestErrorCodes(t,
+		&ErrorCode{Code: "test_store", Err: errStore},
+		&ErrorCode{Code: "test_order_store", Err: errOrderStore},
+		&ErrorCode{Code: "test_store_again", Err: errStore})
+
+	for i := 0; i < 50; i++ {
+		if code := FindErrorCodeOf(fmt.Errorf("save: %w", errOrderStore)); code == nil || code.Code != "test_order_store" {
+			t.Fatalf("code of the order store error is %+v", code)
+		}
+		if code := FindErrorCodeOf(fmt.Errorf("save: %w", errStore)); code == nil || code.Code != "test_store" {
+			t.Fatalf("code of the store error is %+v", code)
+		}
+		if code := FindErrorCodeOf(errors.Join(errors.New("other"), errStore, errOrderStore)); code == nil || code.Code != "test_store" {
+			t.Fatalf("code of the joined errors is %+v", code)
+		}
+	}
+
+	UnregisterErrorCode("test_store")
+	if code := FindErrorCodeOf(errStore); code == nil || code.Code != "test_store_again" {
+		t.Errorf("code after unregistering is %+v", code)
+	}
+
+	// A replaced code keeps its place.
+	RegisterErrorCode(&ErrorCode{Code: "test_store", Err: errStore})
+	RegisterErrorCode(&ErrorCode{Code: "test_store_again", Err: errStore, Severity: ERROR_SEVERITY_INFO})
+	if code := FindErrorCodeOf(errStore); code == nil || code.Code != "test_store_again" || code.Severity != ERROR_SEVERITY_INFO {
+		t.Errorf("code after replacing is %+v", code)
+	}
+}
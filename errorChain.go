@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ERROR_STACK_DEPTH is the maximum number of frames kept by the wrapped errors, printed by the %+v verb.
+const ERROR_STACK_DEPTH int = 32
+
+var (
+	captureErrorQuery = false
+
+	redactStringPattern = regexp.MustCompile(`(?i)N?'(?:[^']|'')*'`)
+	redactNumberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// SetCaptureErrorQuery sets whether the QueryError of a failed action keeps its query, redacted by RedactQuery.
+// The query is not kept by default, as it may hold personal data the redaction misses, e.g. in identifiers.
+func SetCaptureErrorQuery(capture bool) {
+	captureErrorQuery = capture
+}
+
+// RedactQuery replaces the string and number literals of a query with ?, so it can be logged.
+func RedactQuery(query string) string {
+	result := redactStringPattern.ReplaceAllString(query, "'?'")
+	return redactNumberPattern.ReplaceAllString(result, "?")
+}
+
+// QueryError is the error of the query of a catalogued action, keeping its cause for errors.Is and errors.As.
+// Its message is the message of the cause, so the clients receive the same message as before.
+// The %+v verb prints the action, the query and the stack, followed by the chain of causes.
+type QueryError struct {
+	Controller string
+	Action     string
+
+	// Query is the redacted query text, only set by SetCaptureErrorQuery.
+	Query string
+	Err   error
+
+	stack []uintptr
+}
+
+// NewQueryError wraps the error of the query of an action. It returns nil if err is nil.
+func NewQueryError(controller string, action string, query string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	result := &QueryError{Controller: controller, Action: action, Err: err, stack: callers()}
+	if captureErrorQuery {
+		result.Query = RedactQuery(query)
+	}
+	return result
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *QueryError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+func (e *QueryError) errorDetail() string {
+	builder := &strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s/%s: %s", e.Controller, e.Action, e.Err.Error()))
+	if e.Query != "" {
+		builder.WriteString("\nquery: ")
+		builder.WriteString(e.Query)
+	}
+	return builder.String()
+}
+
+func (e *QueryError) errorStack() []uintptr {
+	return e.stack
+}
+
+// wrappedError replaces the message of an error, keeping it as cause.
+type wrappedError struct {
+	message string
+	err     error
+	stack   []uintptr
+}
+
+func newWrappedError(message string, err error) error {
+	return &wrappedError{message: message, err: err, stack: callers()}
+}
+
+func (e *wrappedError) Error() string {
+	return e.message
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+func (e *wrappedError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+func (e *wrappedError) errorStack() []uintptr {
+	return e.stack
+}
+
+// PanicError is a recovered panic, e.g. of a listener event, keeping the stack of the panicking goroutine.
+// It unwraps to the panic value when this value is an error.
+type PanicError struct {
+	Value interface{}
+
+	stack []uintptr
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+func (e *PanicError) errorStack() []uintptr {
+	return e.stack
+}
+
+// recoverError calls a function, returning its error, or a PanicError if it panics.
+func recoverError(call func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Value: value, stack: callers()}
+		}
+	}()
+
+	return call()
+}
+
+// formatError formats an error as the fmt verbs do, except %+v which writes the chain of its causes.
+func formatError(s fmt.State, verb rune, err error) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		writeErrorChain(s, err)
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", err.Error())
+	default:
+		io.WriteString(s, err.Error())
+	}
+}
+
+// writeErrorChain writes an error and its causes, one per "caused by:" line, with the stacks they captured.
+// For the errors with several causes, e.g. SqlDomainError, the chain follows the last one.
+func writeErrorChain(w io.Writer, err error) {
+	for i := 0; err != nil; i++ {
+		if i > 0 {
+			io.WriteString(w, "\ncaused by: ")
+		}
+
+		if e, ok := err.(interface{ errorDetail() string }); ok {
+			io.WriteString(w, e.errorDetail())
+		} else {
+			io.WriteString(w, err.Error())
+		}
+
+		if e, ok := err.(interface{ errorStack() []uintptr }); ok {
+			writeErrorStack(w, e.errorStack())
+		}
+
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			err = FindLast(e.Unwrap(), func(cause error) bool { return cause != nil })
+		default:
+			err = errors.Unwrap(err)
+		}
+	}
+}
+
+func writeErrorStack(w io.Writer, stack []uintptr) {
+	if len(stack) == 0 {
+		return
+	}
+
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(w, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			return
+		}
+	}
+}
+
+// callers returns the stack of the caller of the function creating an error.
+func callers() []uintptr {
+	result := make([]uintptr, ERROR_STACK_DEPTH)
+	return result[:runtime.Callers(3, result)]
+}
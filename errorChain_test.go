@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"select * from Orders2 where Name = N'O''Brien' and Id = 42 and Amount > 3.5": "select * from Orders2 where Name = '?' and Id = ? and Amount > ?",
+		"exec GetOrders @from = '2024-01-01', @top = 10":                              "exec GetOrders @from = '?', @top = ?",
+		"select 1 -- it's": "select ? -- it's",
+	}
+
+	for query, expected := range tests {
+		if result := RedactQuery(query); result != expected {
+			t.Errorf("redacted query of %q is %q, expected %q", query, result, expected)
+		}
+	}
+}
+
+func TestNewQueryErrorCapturesRedactedQuery(t *testing.T) {
+	capture := captureErrorQuery
+	t.Cleanup(func() { SetCaptureErrorQuery(capture) })
+
+	cause := errors.New("connection reset")
+	query := "select * from Orders where Email = 'jdoe@example.com'"
+
+	if err := NewQueryError("Orders", "Get", query, nil); err != nil {
+		t.Errorf("query error of nil is %v", err)
+	}
+
+	SetCaptureErrorQuery(false)
+	err := NewQueryError("Orders", "Get", query, cause)
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Query != "" || !errors.Is(err, cause) || err.Error() != cause.Error() {
+		t.Errorf("query error is %+v", queryErr)
+	}
+	if detail := fmt.Sprintf("%+v", err); strings.Contains(detail, "query:") || strings.Contains(detail, "jdoe") {
+		t.Errorf("query error without capture prints %s", detail)
+	}
+
+	SetCaptureErrorQuery(true)
+	err = NewQueryError("Orders", "Get", query, cause)
+	if !errors.As(err, &queryErr) || queryErr.Query != "select * from Orders where Email = '?'" {
+		t.Errorf("query error is %+v", queryErr)
+	}
+	if detail := fmt.Sprintf("%+v", err); !strings.Contains(detail, "\nquery: select * from Orders where Email = '?'") || strings.Contains(detail, "jdoe") {
+		t.Errorf("query error with capture prints %s", detail)
+	}
+}
+
+func TestQueryErrorFormatPrintsCauses(t *testing.T) {
+	sqlErr := &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "2627", Procedure: "SaveOrder", Line: 12,
+		Message: "Violation of UNIQUE KEY constraint 'UQ_Orders_Number'. The duplicate key value is (A-1)."}
+	classified := ClassifySqlError(sqlErr)
+	err := NewQueryError("Orders", "Save", "", newWrappedError("the order cannot be saved", classified))
+
+	detail := fmt.Sprintf("%+v", err)
+	lines := []string{
+		"Orders/Save: the order cannot be saved",
+		"caused by: the order cannot be saved",
+		"caused by: Violation of UNIQUE KEY constraint 'UQ_Orders_Number'. The duplicate key value is (A-1).",
+		"caused by: mssql 2627: Violation of UNIQUE KEY constraint 'UQ_Orders_Number'. The duplicate key value is (A-1). (procedure SaveOrder, line 12)",
+	}
+	index := 0
+	for _, e := range lines {
+		i := strings.Index(detail[index:], e)
+		if i < 0 {
+			t.Fatalf("%q is not printed in order in:\n%s", e, detail)
+		}
+		index += i + len(e)
+	}
+	if count := strings.Count(detail, "caused by: "); count != 3 {
+		t.Errorf("%d causes are printed in:\n%s", count, detail)
+	}
+	if !strings.Contains(detail, "TestQueryErrorFormatPrintsCauses") {
+		t.Errorf("the stack is not printed in:\n%s", detail)
+	}
+
+	if message := fmt.Sprintf("%v", err); message != "the order cannot be saved" {
+		t.Errorf("message is %q", message)
+	}
+	if quoted := fmt.Sprintf("%q", err); quoted != `"the order cannot be saved"` {
+		t.Errorf("quoted message is %s", quoted)
+	}
+	if !errors.Is(err, ERROR_SQL_UNIQUE_VIOLATION) || !errors.Is(err, sqlErr) {
+		t.Errorf("error %v does not match its causes", err)
+	}
+}
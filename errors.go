@@ -3,45 +3,78 @@ package utils
 import (
 	"errors"
 	"net/http"
-	"reflect"
+	"strings"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+const (
+	VALIDATE_ERROR_CODE string = "888"
+
+	// VALIDATE_METADATA_FIELD and VALIDATE_METADATA_PARAM_PREFIX are the metadata keys of the ErrorInfo details
+	// carrying the code and params of a FieldViolation through gRPC.
+	VALIDATE_METADATA_FIELD        string = "field"
+	VALIDATE_METADATA_PARAM_PREFIX string = "param."
 )
 
 type ISqlError interface {
 	SQLErrorMessage() string
 }
 
-// HandleSqlError handles SQL errors by returning a new error object that contains the SQL error message.
-// If the provided error is not of type mssql.Error, the function simply returns the original error.
+// HandleSqlError handles SQL errors by returning a new error object that contains the SQL error message,
+// and keeps the SQL error as cause for errors.Is and errors.As.
+// The SQL errors are found through the wrappers of the error by the extractors of ExtractSqlError, so TYPE_SQL_ERROR is no longer required.
+// The errors classified by ClassifySqlError are returned as a *SqlDomainError, the others as a *SqlError.
+// If the provided error is not a SQL error, or is already handled, the function simply returns the original error.
 func HandleSqlError(err error) error {
-	if reflect.TypeOf(err) != TYPE_SQL_ERROR {
+	var handled *SqlError
+	if errors.As(err, &handled) {
 		return err
 	}
 
-	e := err.(ISqlError)
-	return errors.New(e.SQLErrorMessage())
+	if classified := ClassifySqlError(err); classified != nil {
+		return classified
+	}
+
+	if result := ExtractSqlError(err); result != nil {
+		return result
+	}
+
+	return err
 }
 
-// HandleGrpcError handles gRPC errors by returning a new error object that contains the gRPC error message.
+// HandleGrpcError handles gRPC errors by returning a new error object that contains the gRPC error message,
+// and keeps the gRPC error as cause.
+// When the status carries a registered error code, the new error is an ICodedError, see LocalizeError.
 // If the provided error is not a gRPC error, the function simply returns the original error.
 func HandleGrpcError(err error) error {
 	if e, ok := status.FromError(err); ok {
 		if e.Code() == codes.InvalidArgument {
 			return NewValidateHttpError(err)
 		}
-		return errors.New(e.Message())
+		if info := grpcErrorInfoOf(e); info != nil && FindErrorCode(info.Reason) != nil {
+			return &codedError{code: info.Reason, message: e.Message(), params: info.Metadata, err: err}
+		}
+		return newWrappedError(e.Message(), err)
 	}
 	return err
 }
 
+// NewValidateHttpError converts an InvalidArgument gRPC status into an IValidateErrorDetails,
+// reading its field violations from the BadRequest details written by ToGrpcError.
+// A status without details has a single violation, whose field is the status message.
 func NewValidateHttpError(err error) error {
 	if e, ok := status.FromError(err); ok {
 		if e.Code() == codes.InvalidArgument {
-			return &validateError{err: "888", field: e.Message(), status: http.StatusBadRequest}
+			if violations := grpcFieldViolations(e); len(violations) > 0 {
+				return &validateError{err: VALIDATE_ERROR_CODE, field: violations[0].Field, status: http.StatusBadRequest, violations: violations, cause: err}
+			}
+			return &validateError{err: VALIDATE_ERROR_CODE, field: e.Message(), status: http.StatusBadRequest, cause: err}
 		}
-		return errors.New(e.Message())
+		return newWrappedError(e.Message(), err)
 	}
 
 	return err
@@ -53,10 +86,47 @@ type IValidateError interface {
 	Field() string
 }
 
+// FieldViolation is an invalid field of a request.
+// Field is the path of the field, e.g. "items[2].quantity", and Code the reason it is invalid, e.g. "required".
+// Params hold the values of the rule, e.g. {"min": "1"}, for the client to render its own message.
+type FieldViolation struct {
+	Field   string            `json:"field"`
+	Code    string            `json:"code,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+// IValidateErrorDetails is an IValidateError listing all the invalid fields of a request.
+type IValidateErrorDetails interface {
+	IValidateError
+	Violations() []FieldViolation
+}
+
+// NewValidateError returns an IValidateErrorDetails with the given violations,
+// whose Field is the field of the first violation.
+func NewValidateError(violations ...FieldViolation) error {
+	result := &validateError{err: VALIDATE_ERROR_CODE, status: http.StatusBadRequest, violations: violations}
+	if len(violations) > 0 {
+		result.field = violations[0].Field
+	}
+	return result
+}
+
+// ValidateErrorViolations returns the violations of a validation error.
+// An IValidateError without details has a single violation of its field.
+func ValidateErrorViolations(err IValidateError) []FieldViolation {
+	if e, ok := err.(IValidateErrorDetails); ok && len(e.Violations()) > 0 {
+		return e.Violations()
+	}
+	return []FieldViolation{{Field: err.Field(), Code: err.Error()}}
+}
+
 type validateError struct {
-	err    string
-	field  string
-	status int
+	err        string
+	field      string
+	status     int
+	violations []FieldViolation
+	cause      error
 }
 
 func (s *validateError) Error() string {
@@ -70,3 +140,89 @@ func (s *validateError) Field() string {
 func (s *validateError) Status() int {
 	return s.status
 }
+
+func (s *validateError) Unwrap() error {
+	return s.cause
+}
+
+func (s *validateError) Violations() []FieldViolation {
+	if len(s.violations) == 0 && s.field != "" {
+		return []FieldViolation{{Field: s.field, Code: s.err}}
+	}
+	return s.violations
+}
+
+// validateGrpcDetails returns the gRPC details of a validation error:
+// a BadRequest listing the violations, followed by an ErrorInfo per violation holding its code and params.
+func validateGrpcDetails(err IValidateError, domain string) []protoiface.MessageV1 {
+	violations := ValidateErrorViolations(err)
+	request := &errdetails.BadRequest{}
+	result := []protoiface.MessageV1{request}
+
+	for _, e := range violations {
+		description := e.Message
+		if description == "" {
+			description = e.Code
+		}
+		request.FieldViolations = append(request.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: e.Field, Description: description})
+
+		metadata := map[string]string{VALIDATE_METADATA_FIELD: e.Field}
+		for k, v := range e.Params {
+			metadata[VALIDATE_METADATA_PARAM_PREFIX+k] = v
+		}
+		result = append(result, &errdetails.ErrorInfo{Reason: e.Code, Domain: domain, Metadata: metadata})
+	}
+
+	return result
+}
+
+// grpcFieldViolations reads the violations of the details written by validateGrpcDetails.
+// The ErrorInfo details with a field are matched with the BadRequest violations in order.
+func grpcFieldViolations(s *status.Status) []FieldViolation {
+	var result []FieldViolation
+	var infos []*errdetails.ErrorInfo
+
+	for _, d := range s.Details() {
+		switch e := d.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range e.FieldViolations {
+				result = append(result, FieldViolation{Field: v.Field, Message: v.Description})
+			}
+		case *errdetails.ErrorInfo:
+			if _, ok := e.Metadata[VALIDATE_METADATA_FIELD]; ok {
+				infos = append(infos, e)
+			}
+		}
+	}
+
+	for i := 0; i < len(result) && i < len(infos); i++ {
+		if infos[i].Metadata[VALIDATE_METADATA_FIELD] != result[i].Field {
+			continue
+		}
+
+		result[i].Code = infos[i].Reason
+		if result[i].Message == result[i].Code {
+			result[i].Message = ""
+		}
+		for k, v := range infos[i].Metadata {
+			if name, ok := strings.CutPrefix(k, VALIDATE_METADATA_PARAM_PREFIX); ok {
+				if result[i].Params == nil {
+					result[i].Params = map[string]string{}
+				}
+				result[i].Params[name] = v
+			}
+		}
+	}
+
+	return result
+}
+
+// grpcErrorInfoOf returns the first ErrorInfo detail of a status, or nil.
+func grpcErrorInfoOf(s *status.Status) *errdetails.ErrorInfo {
+	for _, d := range s.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testSqlMessageError is an ISqlError of a driver without extractor.
+type testSqlMessageError struct {
+	message string
+}
+
+func (e *testSqlMessageError) Error() string {
+	return "driver: " + e.message
+}
+
+func (e *testSqlMessageError) SQLErrorMessage() string {
+	return e.message
+}
+
+func TestHandleSqlError(t *testing.T) {
+	unique := testMssqlError{Number: SQL_ERROR_NUMBER_UNIQUE_CONSTRAINT, Message: "Violation of UNIQUE KEY constraint 'UQ_Orders_Number'."}
+	invalidObject := testMssqlError{Number: 208, Message: "Invalid object name 'dbo.Order'."}
+
+	classified := HandleSqlError(fmt.Errorf("save order: %w", unique))
+	var domainErr *SqlDomainError
+	if !errors.As(classified, &domainErr) || domainErr.Kind != ERROR_SQL_UNIQUE_VIOLATION || domainErr.Constraint != "UQ_Orders_Number" ||
+		!errors.Is(classified, ERROR_SQL_UNIQUE_VIOLATION) || classified.Error() != unique.Message {
+		t.Errorf("classified error is %+v", classified)
+	}
+
+	unclassified := HandleSqlError(fmt.Errorf("get order: %w", invalidObject))
+	var sqlErr *SqlError
+	if !errors.As(unclassified, &sqlErr) || errors.As(unclassified, &domainErr) || sqlErr.Driver != SQL_DRIVER_MSSQL || sqlErr.Code != "208" ||
+		unclassified.Error() != invalidObject.Message || !errors.Is(unclassified, invalidObject) {
+		t.Errorf("unclassified error is %+v", unclassified)
+	}
+	if again := HandleSqlError(unclassified); again != unclassified {
+		t.Errorf("handled error is handled again into %+v", again)
+	}
+
+	other := &testSqlMessageError{message: "database is locked"}
+	if result := HandleSqlError(other); !errors.As(result, &sqlErr) || sqlErr.Driver != "" || result.Error() != "database is locked" {
+		t.Errorf("error of an ISqlError is %+v", result)
+	}
+
+	plain := errors.New("connection refused")
+	if result := HandleSqlError(plain); result != plain {
+		t.Errorf("error without SQL error is %+v", result)
+	}
+}
+
+// testFieldError is an IValidateError without violations, as the validators written before IValidateErrorDetails.
+type testFieldError struct{}
+
+func (testFieldError) Error() string { return "required" }
+func (testFieldError) Status() int   { return http.StatusBadRequest }
+func (testFieldError) Field() string { return "customerId" }
+
+func TestValidateGrpcDetails(t *testing.T) {
+	violations := []FieldViolation{
+		{Field: "items[2].quantity", Code: "min", Params: map[string]string{"min": "1"}},
+		{Field: "email", Code: "format", Message: "The email is invalid."},
+	}
+
+	e, _ := status.FromError(ToGrpcError(NewValidateError(violations...)))
+	if e.Code() != codes.InvalidArgument || e.Message() != "items[2].quantity" {
+		t.Errorf("status is %v", e)
+	}
+
+	var request *errdetails.BadRequest
+	var infos []*errdetails.ErrorInfo
+	for _, d := range e.Details() {
+		switch detail := d.(type) {
+		case *errdetails.BadRequest:
+			request = detail
+		case *errdetails.ErrorInfo:
+			infos = append(infos, detail)
+		}
+	}
+	if request == nil || len(request.FieldViolations) != 2 || request.FieldViolations[0].Description != "min" ||
+		request.FieldViolations[1].Description != "The email is invalid." {
+		t.Fatalf("bad request is %v", request)
+	}
+	if len(infos) != 3 || infos[0].Reason != VALIDATE_ERROR_CODE || infos[1].Reason != "min" ||
+		infos[1].Metadata[VALIDATE_METADATA_FIELD] != "items[2].quantity" || infos[1].Metadata[VALIDATE_METADATA_PARAM_PREFIX+"min"] != "1" {
+		t.Errorf("error infos are %v", infos)
+	}
+
+	var details IValidateErrorDetails
+	if err := HandleGrpcError(e.Err()); !errors.As(err, &details) || details.Field() != "items[2].quantity" || !reflect.DeepEqual(details.Violations(), violations) {
+		t.Errorf("violations read back are %+v", details)
+	}
+
+	e, _ = status.FromError(ToGrpcError(testFieldError{}))
+	if err := HandleGrpcError(e.Err()); !errors.As(err, &details) ||
+		!reflect.DeepEqual(details.Violations(), []FieldViolation{{Field: "customerId", Code: "required"}}) {
+		t.Errorf("violations of an error without details are %+v", details)
+	}
+}
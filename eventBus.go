@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// EVENT_TOPIC_SEPARATOR separates the namespaces of a topic name, e.g. "order.created".
+	EVENT_TOPIC_SEPARATOR string = "."
+	// EVENT_TOPIC_WILDCARD matches one segment of a topic name in a pattern, e.g. "order.*".
+	EVENT_TOPIC_WILDCARD string = "*"
+	// EVENT_TOPIC_WILDCARD_ALL matches the remaining segments of a topic name, at the end of a pattern, e.g. "order.**".
+	EVENT_TOPIC_WILDCARD_ALL string = "**"
+)
+
+var (
+	ERROR_EVENT_TYPE_MISMATCH = errors.New("event_type_mismatch")
+	ERROR_EVENT_TOPIC_INVALID = errors.New("event_topic_invalid")
+
+	eventTopicTypes      = map[string]reflect.Type{}
+	eventTopicTypesMutex sync.Mutex
+
+	defaultEventBus = NewEventBus()
+)
+
+// Topic is a typed topic of an EventBus, declared once and shared by its publishers and subscribers:
+//
+//	var ORDER_CREATED = utils.NewTopic[OrderCreated]("order.created")
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic declares a topic of the given name and event type.
+// It panics if the name is empty, holds a wildcard, or is already declared with another type.
+func NewTopic[T any](name string) Topic[T] {
+	if name == "" || strings.Contains(name, EVENT_TOPIC_WILDCARD) {
+		panic(fmt.Sprintf("utils: invalid topic name %q", name))
+	}
+
+	eventType := reflect.TypeOf((*T)(nil)).Elem()
+
+	eventTopicTypesMutex.Lock()
+	defer eventTopicTypesMutex.Unlock()
+
+	if e, ok := eventTopicTypes[name]; ok && e != eventType {
+		panic(fmt.Sprintf("utils: topic %q is declared with the types %s and %s", name, e, eventType))
+	}
+	eventTopicTypes[name] = eventType
+
+	return Topic[T]{name: name}
+}
+
+// Name returns the name of the topic.
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// EventBus delivers in process the events published on a topic to its subscribers,
+// and to the subscribers of the patterns matching the topic, see SubscribePattern.
+// Each topic and pattern is a ListenerV3 invoked every time, so the subscribers are called by priority of subscription,
+// their errors and panics joined. The subscribers of the topic come first, then the patterns in the order of their first subscription.
+// A topic or a pattern is removed once its last subscriber unsubscribes.
+type EventBus struct {
+	mutex    sync.RWMutex
+	topics   map[string]*eventBusListener
+	patterns []*eventBusListener
+}
+
+// eventBusListener is the listener of a topic or a pattern, and its number of subscribers.
+type eventBusListener struct {
+	name        string
+	listener    IEventListenerV3[context.Context, string, interface{}]
+	subscribers int
+}
+
+// NewEventBus returns a new instance of EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		topics: map[string]*eventBusListener{},
+	}
+}
+
+// DefaultEventBus returns the event bus of the process.
+func DefaultEventBus() *EventBus {
+	return defaultEventBus
+}
+
+// SubscribePattern subscribes to the topics matching a pattern, whose segments are names,
+// EVENT_TOPIC_WILDCARD, or a final EVENT_TOPIC_WILDCARD_ALL, e.g. "order.*" or "**".
+// The handler receives the name of the topic and its event.
+func (b *EventBus) SubscribePattern(pattern string, handler func(ctx context.Context, topic string, event interface{}) error) ISubscription {
+	if handler == nil {
+		return newEmptySubscription()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry := b.find(pattern)
+	if entry == nil {
+		entry = &eventBusListener{name: pattern, listener: NewListenerV3WithMode[context.Context, string, interface{}](LISTENER_MODE_EVERY_TIME)}
+		if strings.Contains(pattern, EVENT_TOPIC_WILDCARD) {
+			b.patterns = append(b.patterns, entry)
+		} else {
+			b.topics[pattern] = entry
+		}
+	}
+	entry.subscribers++
+
+	inner := entry.listener.SubscribeError(handler)
+	return &subscription{done: make(chan struct{}), unsubscribe: func() {
+		inner.Unsubscribe()
+		b.release(entry)
+	}}
+}
+
+// Publish calls the subscribers of a topic, and returns their errors joined.
+// A topic holding a wildcard is rejected with ERROR_EVENT_TOPIC_INVALID, as it is a pattern of subscribers only.
+func (b *EventBus) Publish(ctx context.Context, topic string, event interface{}) error {
+	if err := validateTopic(topic); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, e := range b.listeners(topic) {
+		errs = append(errs, e.InvokeAllErr(ctx, topic, event))
+	}
+	return errors.Join(errs...)
+}
+
+// PublishAsync calls the subscribers of a topic concurrently with the given options, and returns without waiting for them.
+// A topic holding a wildcard is rejected as Publish does, the result being done at once with the error.
+func (b *EventBus) PublishAsync(ctx context.Context, topic string, event interface{}, options InvokeOptions) IInvokeResult {
+	result := newInvokeResult()
+	if err := validateTopic(topic); err != nil {
+		result.err = err
+		close(result.done)
+		return result
+	}
+
+	listeners := b.listeners(topic)
+	results := make([]IInvokeResult, len(listeners))
+	for i, e := range listeners {
+		results[i] = e.InvokeAsync(ctx, topic, event, options)
+	}
+
+	go func() {
+		defer close(result.done)
+
+		errs := make([]error, len(results))
+		for i, e := range results {
+			errs[i] = e.Wait()
+		}
+		result.err = errors.Join(errs...)
+	}()
+	return result
+}
+
+// validateTopic returns ERROR_EVENT_TOPIC_INVALID if a published topic holds a wildcard.
+func validateTopic(topic string) error {
+	if strings.Contains(topic, EVENT_TOPIC_WILDCARD) {
+		return fmt.Errorf("%w: %q holds a wildcard", ERROR_EVENT_TOPIC_INVALID, topic)
+	}
+	return nil
+}
+
+// find returns the listener of a topic or a pattern, or nil, the mutex being locked.
+func (b *EventBus) find(name string) *eventBusListener {
+	if !strings.Contains(name, EVENT_TOPIC_WILDCARD) {
+		return b.topics[name]
+	}
+	return Find(b.patterns, func(e *eventBusListener) bool {
+		return e.name == name
+	})
+}
+
+// release removes a subscriber of a listener, and the listener with its last subscriber.
+func (b *EventBus) release(entry *eventBusListener) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry.subscribers--
+	if entry.subscribers > 0 {
+		return
+	}
+
+	if b.topics[entry.name] == entry {
+		delete(b.topics, entry.name)
+		return
+	}
+	b.patterns = Where(b.patterns, func(e *eventBusListener) bool {
+		return e != entry
+	})
+}
+
+// listeners returns the listener of a topic, followed by the listeners of the patterns matching it.
+func (b *EventBus) listeners(topic string) []IEventListenerV3[context.Context, string, interface{}] {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var result []IEventListenerV3[context.Context, string, interface{}]
+	if e, ok := b.topics[topic]; ok {
+		result = append(result, e.listener)
+	}
+	for _, e := range b.patterns {
+		if MatchTopic(e.name, topic) {
+			result = append(result, e.listener)
+		}
+	}
+	return result
+}
+
+// MatchTopic reports whether a topic name matches a pattern of SubscribePattern.
+func MatchTopic(pattern string, topic string) bool {
+	patterns := strings.Split(pattern, EVENT_TOPIC_SEPARATOR)
+	names := strings.Split(topic, EVENT_TOPIC_SEPARATOR)
+
+	for i, e := range patterns {
+		if e == EVENT_TOPIC_WILDCARD_ALL && i == len(patterns)-1 {
+			return true
+		}
+		if i >= len(names) || (e != EVENT_TOPIC_WILDCARD && e != names[i]) {
+			return false
+		}
+	}
+	return len(patterns) == len(names)
+}
+
+// SubscribeTopic subscribes a typed handler to a topic of the bus.
+func SubscribeTopic[T any](bus *EventBus, topic Topic[T], handler func(ctx context.Context, event T) error) ISubscription {
+	if handler == nil {
+		return newEmptySubscription()
+	}
+
+	return bus.SubscribePattern(topic.name, func(ctx context.Context, name string, event interface{}) error {
+		value, ok := event.(T)
+		if !ok {
+			return fmt.Errorf("%w: topic %s received %T", ERROR_EVENT_TYPE_MISMATCH, name, event)
+		}
+		return handler(ctx, value)
+	})
+}
+
+// PublishTopic publishes a typed event on a topic of the bus, and returns the errors of its subscribers joined.
+func PublishTopic[T any](ctx context.Context, bus *EventBus, topic Topic[T], event T) error {
+	return bus.Publish(ctx, topic.name, event)
+}
+
+// PublishTopicAsync publishes a typed event on a topic of the bus, and returns without waiting for its subscribers.
+func PublishTopicAsync[T any](ctx context.Context, bus *EventBus, topic Topic[T], event T, options InvokeOptions) IInvokeResult {
+	return bus.PublishAsync(ctx, topic.name, event, options)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEventBusPatternOrder(t *testing.T) {
+	bus := NewEventBus()
+
+	var calls []string
+	for _, e := range []string{"order.**", "**", "order.created", "*.created", "order.*"} {
+		pattern := e
+		bus.SubscribePattern(pattern, func(ctx context.Context, topic string, event interface{}) error {
+			calls = append(calls, pattern)
+			return nil
+		})
+	}
+
+	for i := 0; i < 10; i++ {
+		calls = nil
+		if err := bus.Publish(context.Background(), "order.created", 1); err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"order.created", "order.**", "**", "*.created", "order.*"}; !reflect.DeepEqual(calls, expected) {
+			t.Fatalf("calls are %v, expected %v", calls, expected)
+		}
+	}
+}
+
+func TestEventBusRemovesListenersWithoutSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	handler := func(ctx context.Context, topic string, event interface{}) error {
+		return nil
+	}
+
+	first := bus.SubscribePattern("order.*", handler)
+	second := bus.SubscribePattern("order.*", handler)
+	topic := bus.SubscribePattern("order.created", handler)
+
+	first.Unsubscribe()
+	first.Unsubscribe()
+	if len(bus.patterns) != 1 {
+		t.Fatalf("patterns are %v after the first unsubscribe", bus.patterns)
+	}
+
+	second.Unsubscribe()
+	topic.Unsubscribe()
+	if len(bus.patterns) != 0 || len(bus.topics) != 0 {
+		t.Errorf("bus has %d patterns and %d topics", len(bus.patterns), len(bus.topics))
+	}
+
+	called := false
+	bus.SubscribePattern("order.*", func(ctx context.Context, topic string, event interface{}) error {
+		called = true
+		return nil
+	})
+	bus.Publish(context.Background(), "order.created", 1)
+	if !called {
+		t.Error("subscriber of a removed pattern is not called")
+	}
+}
+
+func TestEventBusRejectsWildcardTopics(t *testing.T) {
+	bus := NewEventBus()
+
+	var calls []string
+	for _, e := range []string{"order.*", "order.**", "**"} {
+		pattern := e
+		bus.SubscribePattern(pattern, func(ctx context.Context, topic string, event interface{}) error {
+			calls = append(calls, pattern)
+			return nil
+		})
+	}
+
+	for _, topic := range []string{"order.*", "order.**", "*", "**"} {
+		if err := bus.Publish(context.Background(), topic, 1); !errors.Is(err, ERROR_EVENT_TOPIC_INVALID) {
+			t.Errorf("publishing on %s returned %v", topic, err)
+		}
+
+		result := bus.PublishAsync(context.Background(), topic, 1, InvokeOptions{})
+		select {
+		case <-result.Done():
+		default:
+			t.Fatalf("asynchronous publishing on %s is pending", topic)
+		}
+		if err := result.Err(); !errors.Is(err, ERROR_EVENT_TOPIC_INVALID) {
+			t.Errorf("asynchronous publishing on %s returned %v", topic, err)
+		}
+	}
+
+	if len(calls) != 0 {
+		t.Errorf("patterns %v are called by the topics holding a wildcard", calls)
+	}
+	if code := FindErrorCodeOf(bus.Publish(context.Background(), "order.*", 1)); code == nil || code.Code != ERROR_EVENT_TOPIC_INVALID.Error() {
+		t.Errorf("error code is %+v", code)
+	}
+}
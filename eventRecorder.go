@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// RecordedEvent is an event recorded by an EventRecorder.
+type RecordedEvent struct {
+	Topic string
+	Event interface{}
+}
+
+// EventRecorder records the events published on the topics matching a pattern, in their order of publication,
+// so the tests of a service can assert what it published:
+//
+//	recorder := utils.NewEventRecorder(bus, "order.**")
+//	defer recorder.Close()
+//	...
+//	created := utils.RecordedTopicEvents(recorder, ORDER_CREATED)
+type EventRecorder struct {
+	mutex        sync.Mutex
+	events       []RecordedEvent
+	subscription ISubscription
+}
+
+// NewEventRecorder returns an EventRecorder subscribed to a pattern of the bus, see EventBus.SubscribePattern.
+func NewEventRecorder(bus *EventBus, pattern string) *EventRecorder {
+	result := &EventRecorder{}
+	result.subscription = bus.SubscribePattern(pattern, func(ctx context.Context, topic string, event interface{}) error {
+		result.mutex.Lock()
+		defer result.mutex.Unlock()
+
+		result.events = append(result.events, RecordedEvent{Topic: topic, Event: event})
+		return nil
+	})
+	return result
+}
+
+// Events returns a copy of the recorded events.
+func (r *EventRecorder) Events() []RecordedEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result := make([]RecordedEvent, len(r.events))
+	copy(result, r.events)
+	return result
+}
+
+// Reset forgets the recorded events.
+func (r *EventRecorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = nil
+}
+
+// Close stops recording, keeping the recorded events.
+func (r *EventRecorder) Close() {
+	r.subscription.Unsubscribe()
+}
+
+// RecordedTopicEvents returns the recorded events of a topic.
+func RecordedTopicEvents[T any](recorder *EventRecorder, topic Topic[T]) []T {
+	var result []T
+	for _, e := range recorder.Events() {
+		if value, ok := e.Event.(T); ok && e.Topic == topic.name {
+			result = append(result, value)
+		}
+	}
+	return result
+}
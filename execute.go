@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -34,15 +33,16 @@ func SetIsDevelopment(isDev bool) {
 }
 
 func Execute[R, T any](db IGormDB[R, T], controller string, action string, claims IClaims, request interface{}, result interface{}) error {
-	queryText := FindQueryWithinParamAndUser(controller, action, ToSqlScript(request, "Model", IGNORE_FIELDS...), claims, replaceClaims)
-	if queryText == "" {
-		return errors.New("action_not_found")
+	values := TemplateValues{}.Bind(request).Set(TEMPLATE_QUERY_PARAMS, ToSqlScript(request, "Model", IGNORE_FIELDS...))
+	queryText, args, err := renderQuery(controller, action, claims, values)
+	if err != nil {
+		return err
 	}
 
-	rows, queryError := any(db.Raw(queryText)).(IDB[R]).Rows()
+	rows, queryError := any(db.Raw(queryText, args...)).(IDB[R]).Rows()
 	if queryError != nil {
 		consoleError("Execute", controller, action, queryText, queryError)
-		return HandleSqlError(queryError)
+		return NewQueryError(controller, action, queryText, HandleSqlError(queryError))
 	}
 
 	consoleQuery("Execute", controller, action, queryText)
@@ -50,22 +50,22 @@ func Execute[R, T any](db IGormDB[R, T], controller string, action string, claim
 	defer any(rows).(ISqlRow).Close()
 	if errScan := scanResults(db, rows, result); errScan != nil {
 		consoleError("Execute", controller, action, queryText, errScan)
-		return HandleSqlError(errScan)
+		return NewQueryError(controller, action, queryText, HandleSqlError(errScan))
 	}
 
 	return nil
 }
 
 func ExecuteId[R ISqlRow, T any](db IGormDB[R, T], controller string, action string, claims IClaims, id interface{}, result interface{}) error {
-	queryText := FindQueryWithinUser(controller, action, claims, replaceClaims)
-	if queryText == "" {
-		return errors.New("action_not_found")
+	queryText, args, err := renderQuery(controller, action, claims, TemplateValues{})
+	if err != nil {
+		return err
 	}
 
-	rows, queryError := any(db.Raw(queryText, id)).(IDB[R]).Rows()
+	rows, queryError := any(db.Raw(queryText, append(args, id)...)).(IDB[R]).Rows()
 	if queryError != nil {
 		consoleError("ExecuteId", controller, action, queryText, queryError)
-		return HandleSqlError(queryError)
+		return NewQueryError(controller, action, queryText, HandleSqlError(queryError))
 	}
 
 	consoleQuery("ExecuteId", controller, action, queryText)
@@ -73,22 +73,23 @@ func ExecuteId[R ISqlRow, T any](db IGormDB[R, T], controller string, action str
 	defer any(rows).(ISqlRow).Close()
 	if errScan := scanResults(db, rows, result); errScan != nil {
 		consoleError("ExecuteId", controller, action, queryText, errScan)
-		return HandleSqlError(errScan)
+		return NewQueryError(controller, action, queryText, HandleSqlError(errScan))
 	}
 
 	return nil
 }
 
 func ExecuteMultipleResult[R, T any](db IGormDB[R, T], controller string, action string, claims IClaims, request interface{}, results ...interface{}) error {
-	queryText := FindQueryWithinParamAndUser(controller, action, ToSqlScript(request, "Model", IGNORE_FIELDS...), claims, replaceClaims)
-	if queryText == "" {
-		return errors.New("action_not_found")
+	values := TemplateValues{}.Bind(request).Set(TEMPLATE_QUERY_PARAMS, ToSqlScript(request, "Model", IGNORE_FIELDS...))
+	queryText, args, err := renderQuery(controller, action, claims, values)
+	if err != nil {
+		return err
 	}
 
-	rows, queryError := any(db.Raw(queryText)).(IDB[R]).Rows()
+	rows, queryError := any(db.Raw(queryText, args...)).(IDB[R]).Rows()
 	if queryError != nil {
 		consoleError("ExecuteMultipleResult", controller, action, queryText, queryError)
-		return HandleSqlError(queryError)
+		return NewQueryError(controller, action, queryText, HandleSqlError(queryError))
 	}
 
 	consoleQuery("ExecuteMultipleResult", controller, action, queryText)
@@ -99,19 +100,19 @@ func ExecuteMultipleResult[R, T any](db IGormDB[R, T], controller string, action
 		consoleError("ExecuteMultipleResult", controller, action, queryText, errScan)
 	}
 
-	return errScan
+	return NewQueryError(controller, action, queryText, errScan)
 }
 
 func ExecuteIdMultipleResult[R, T any](db IGormDB[R, T], controller string, action string, claims IClaims, id interface{}, results ...interface{}) error {
-	queryText := FindQueryWithinUser(controller, action, claims, replaceClaims)
-	if queryText == "" {
-		return errors.New("action_not_found")
+	queryText, args, err := renderQuery(controller, action, claims, TemplateValues{})
+	if err != nil {
+		return err
 	}
 
-	rows, queryError := any(db.Raw(queryText, id)).(IDB[R]).Rows()
+	rows, queryError := any(db.Raw(queryText, append(args, id)...)).(IDB[R]).Rows()
 	if queryError != nil {
 		consoleError("ExecuteIdMultipleResult", controller, action, queryText, queryError)
-		return HandleSqlError(queryError)
+		return NewQueryError(controller, action, queryText, HandleSqlError(queryError))
 	}
 
 	consoleQuery("ExecuteIdMultipleResult", controller, action, queryText)
@@ -122,7 +123,7 @@ func ExecuteIdMultipleResult[R, T any](db IGormDB[R, T], controller string, acti
 		consoleError("ExecuteIdMultipleResult", controller, action, queryText, errScan)
 	}
 
-	return errScan
+	return NewQueryError(controller, action, queryText, errScan)
 }
 
 func FilterPagination[R, T any](db IGormDB[R, T], controller string, action string, claims IClaims, filters interface{}, paging interface{}, results ...interface{}) error {
@@ -130,15 +131,16 @@ func FilterPagination[R, T any](db IGormDB[R, T], controller string, action stri
 	builder.WriteString(ToSqlScript(filters, "Filter", IGNORE_FIELDS...))
 	builder.WriteString("\n")
 	builder.WriteString(ToSqlScript(paging, "Pagination", IGNORE_FIELDS...))
-	queryText := FindQueryWithinParamAndUser(controller, action, builder.String(), claims, replaceClaims)
-	if queryText == "" {
-		return errors.New("action_not_found")
+	values := TemplateValues{}.Bind(filters).Bind(paging).Set(TEMPLATE_QUERY_PARAMS, builder.String())
+	queryText, args, err := renderQuery(controller, action, claims, values)
+	if err != nil {
+		return err
 	}
 
-	rows, queryError := any(db.Raw(queryText)).(IDB[R]).Rows()
+	rows, queryError := any(db.Raw(queryText, args...)).(IDB[R]).Rows()
 	if queryError != nil {
 		consoleError("FilterPagination", controller, action, queryText, queryError)
-		return HandleSqlError(queryError)
+		return NewQueryError(controller, action, queryText, HandleSqlError(queryError))
 	}
 
 	consoleQuery("FilterPagination", controller, action, queryText)
@@ -149,27 +151,19 @@ func FilterPagination[R, T any](db IGormDB[R, T], controller string, action stri
 		consoleError("ExecuteIdMultipleResult", controller, action, queryText, errScan)
 	}
 
-	return errScan
+	return NewQueryError(controller, action, queryText, errScan)
 }
 
-func replaceClaims(input string, claims IClaims) string {
-	if claims == nil {
-		return input
-	}
-
-	input = strings.ReplaceAll(input, "@@userID", fmt.Sprintf("%d", claims.GetId()))
-	input = strings.ReplaceAll(input, "@@clientID", fmt.Sprintf("%d", claims.GetClientId()))
-	input = strings.ReplaceAll(input, "@@unitID", fmt.Sprintf("%d", claims.GetUnitId()))
-	input = strings.ReplaceAll(input, "@@username", fmt.Sprintf("'%s'", Safe(claims.GetUsername())))
-	input = strings.ReplaceAll(input, "@@email", fmt.Sprintf("'%s'", Safe(claims.GetEmail())))
-	input = strings.ReplaceAll(input, "@@fullName", fmt.Sprintf("'%s'", Safe(claims.GetFullname())))
-	input = strings.ReplaceAll(input, "@@phone", fmt.Sprintf("'%s'", Safe(claims.GetPhone())))
-	input = strings.ReplaceAll(input, "@@language", fmt.Sprintf("'%s'", Safe(claims.GetLanguage())))
-	input = strings.ReplaceAll(input, "@@isAdmin", fmt.Sprintf("%d", BoolToInt(claims.GetIsAdmin())))
-	input = strings.ReplaceAll(input, "@@isSystem", fmt.Sprintf("%d", BoolToInt(claims.GetIsSystem())))
-	input = strings.ReplaceAll(input, "@@isBaseLanguage", fmt.Sprintf("%d", BoolToInt(claims.GetIsBaseLanguage())))
-	input = strings.ReplaceAll(input, "[2]", IIF(claims.GetIsBaseLanguage(), "", "2"))
-	return input
+// renderQuery compiles the catalog text of an action and renders it in a single pass with the claims,
+// so values coming from a request can never be mistaken for a placeholder.
+// It returns the query, and the parameters of the claims as set by SetClaimsMode.
+func renderQuery(controller string, action string, claims IClaims, values TemplateValues) (string, []interface{}, error) {
+	template, err := FindQueryTemplate(controller, action)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return template.RenderWithClaimsMode(values, claims, claimsMode)
 }
 
 func scanResults[R, T any](db IGormDB[R, T], rows R, results ...interface{}) error {
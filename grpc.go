@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+const (
+	GRPC_AUTHORIZATION_KEY string = "authorization"
+	GRPC_BEARER_SCHEME     string = "bearer"
+	GRPC_ERROR_DOMAIN      string = "mm-go-utilities"
+)
+
+// ITokenVerifier verifies a bearer token and returns its claims, e.g. JwtVerifier.
+// A service using its own claims type returns it from Verify, and reads it back with ClaimsFromContext.
+type ITokenVerifier interface {
+	Verify(token string) (IClaims, error)
+}
+
+type claimsContextKey struct{}
+
+// ContextWithClaims returns a copy of the context holding the claims.
+func ContextWithClaims(ctx context.Context, claims IClaims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims held by the context, set by ContextWithClaims or the claims interceptors.
+func ClaimsFromContext(ctx context.Context) (IClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(IClaims)
+	return claims, ok && claims != nil
+}
+
+// UnaryClaimsInterceptor returns a unary server interceptor reading the bearer token of the "authorization" metadata
+// into the claims of the context. Calls without a valid token fail with codes.Unauthenticated,
+// except for the given public methods (e.g. "/auth.Auth/Login"), which run without claims when they have no token.
+func UnaryClaimsInterceptor(verifier ITokenVerifier, publicMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticateGrpc(ctx, verifier, info.FullMethod, publicMethods)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamClaimsInterceptor returns a stream server interceptor doing the same as UnaryClaimsInterceptor.
+func StreamClaimsInterceptor(verifier ITokenVerifier, publicMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGrpc(stream.Context(), verifier, info.FullMethod, publicMethods)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// UnaryErrorInterceptor returns a unary server interceptor converting the errors of the handlers with ToGrpcError.
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, ToGrpcError(err)
+	}
+}
+
+// StreamErrorInterceptor returns a stream server interceptor converting the errors of the handlers with ToGrpcError.
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return ToGrpcError(handler(srv, stream))
+	}
+}
+
+// ToGrpcError converts an error into a gRPC status error, so the client receives a meaningful code:
+//   - gRPC status errors are returned as they are
+//   - IValidateError becomes codes.InvalidArgument, with a BadRequest detail listing its violations
+//   - context errors become codes.Canceled and codes.DeadlineExceeded
+//   - SQL errors classified by ClassifySqlError get the gRPC code of their ErrorCode, e.g. codes.AlreadyExists
+//   - the other SQL errors, as recognized by ExtractSqlError, become codes.Internal with the message of ERROR_CODE_SQL,
+//     since the SQL message may name the tables, columns and values of the database
+//   - the errors with a registered ErrorCode, e.g. ERROR_ACTION_NOT_FOUND or the ERROR_JWT_* errors, get its gRPC code
+//   - any other error becomes codes.Unknown, with the message of ERROR_CODE_UNKNOWN instead of its own,
+//     which stays on the server, e.g. in the error returned to the interceptors chained before this one
+//
+// Except for the first case, the status carries an ErrorInfo detail whose reason is the error code
+// ("888" for validation errors, "sql_error" for unclassified SQL errors, ERROR_CODE_UNKNOWN for unknown errors) and whose metadata are its params.
+// The status of a validation error holds the field as message, so HandleGrpcError turns it back into an IValidateError.
+func ToGrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var validate IValidateError
+	if errors.As(err, &validate) {
+		return newGrpcStatus(codes.InvalidArgument, validate.Field(), grpcErrorInfo(validate.Error(), nil), validateGrpcDetails(validate, GRPC_ERROR_DOMAIN)...)
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return newGrpcStatus(codes.Canceled, err.Error(), grpcErrorInfo(err.Error(), nil))
+	case errors.Is(err, context.DeadlineExceeded):
+		return newGrpcStatus(codes.DeadlineExceeded, err.Error(), grpcErrorInfo(err.Error(), nil))
+	}
+
+	if classified := ClassifySqlError(err); classified != nil {
+		err = classified
+	} else if ExtractSqlError(err) != nil {
+		return newGrpcStatus(codes.Internal, sqlErrorMessage(ERROR_LANGUAGE_DEFAULT), grpcErrorInfo(ERROR_CODE_SQL, nil))
+	}
+
+	if code := FindErrorCodeOf(err); code != nil {
+		// A code registered without gRPC code must still fail the call.
+		grpcCode := IIF(code.GrpcCode == codes.OK, codes.Unknown, code.GrpcCode)
+
+		var coded ICodedError
+		if errors.As(err, &coded) {
+			return newGrpcStatus(grpcCode, err.Error(), grpcErrorInfo(code.Code, coded.ErrorParams()))
+		}
+		return newGrpcStatus(grpcCode, err.Error(), grpcErrorInfo(code.Code, nil))
+	}
+
+	// The message of an unknown error may hold internal details, e.g. an address or a login: it is never sent.
+	return newGrpcStatus(codes.Unknown, unknownErrorMessage(ERROR_LANGUAGE_DEFAULT), grpcErrorInfo(ERROR_CODE_UNKNOWN, nil))
+}
+
+// contextServerStream is a grpc.ServerStream whose context is replaced.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticateGrpc reads the bearer token of the incoming metadata into the claims of the context.
+func authenticateGrpc(ctx context.Context, verifier ITokenVerifier, method string, publicMethods []string) (context.Context, error) {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, e := range md.Get(GRPC_AUTHORIZATION_KEY) {
+			scheme, value, found := strings.Cut(e, " ")
+			if found && strings.EqualFold(scheme, GRPC_BEARER_SCHEME) {
+				token = strings.TrimSpace(value)
+				break
+			}
+		}
+	}
+
+	if token == "" {
+		if ComparableContains(method, publicMethods...) {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return nil, ToGrpcError(err)
+	}
+
+	return ContextWithClaims(ctx, claims), nil
+}
+
+// newGrpcStatus returns a status error with the ErrorInfo detail, followed by the given details.
+func newGrpcStatus(code codes.Code, message string, info *errdetails.ErrorInfo, details ...protoiface.MessageV1) error {
+	result := status.New(code, message)
+
+	withDetails, err := result.WithDetails(append([]protoiface.MessageV1{info}, details...)...)
+	if err != nil {
+		return result.Err()
+	}
+
+	return withDetails.Err()
+}
+
+func grpcErrorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: GRPC_ERROR_DOMAIN, Metadata: metadata}
+}
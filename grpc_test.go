@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	testGrpcService      = "test.Orders"
+	testGrpcMethodGet    = "/" + testGrpcService + "/Get"
+	testGrpcMethodLogin  = "/" + testGrpcService + "/Login"
+	testGrpcMethodWatch  = "/" + testGrpcService + "/Watch"
+	testGrpcValidToken   = "valid"
+	testGrpcExpiredToken = "expired"
+)
+
+// testClaims is a claims type of a service, returned by testTokenVerifier instead of *Claims.
+type testClaims struct {
+	Claims
+	TenantId int64
+}
+
+type testTokenVerifier struct{}
+
+func (testTokenVerifier) Verify(token string) (IClaims, error) {
+	switch token {
+	case testGrpcValidToken:
+		return &testClaims{Claims: Claims{Id: 42}, TenantId: 7}, nil
+	case testGrpcExpiredToken:
+		return nil, ERROR_JWT_EXPIRED
+	}
+	return nil, ERROR_JWT_SIGNATURE
+}
+
+// startTestGrpcServer serves a service whose unary methods return the result of handle,
+// and whose stream method sends nothing and returns the result of handle, over a bufconn listener.
+func startTestGrpcServer(t *testing.T, handle func(ctx context.Context) error, options ...grpc.ServerOption) *grpc.ClientConn {
+	unary := func(method string) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+		return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := &emptypb.Empty{}
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &emptypb.Empty{}, handle(ctx)
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+		}
+	}
+
+	server := grpc.NewServer(options...)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: testGrpcService,
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Get", Handler: unary(testGrpcMethodGet)}, {MethodName: "Login", Handler: unary(testGrpcMethodLogin)}},
+		Streams: []grpc.StreamDesc{{StreamName: "Watch", ServerStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return handle(stream.Context())
+		}}},
+	}, struct{}{})
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func testGrpcContext(token string) context.Context {
+	if token == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), GRPC_AUTHORIZATION_KEY, "Bearer "+token)
+}
+
+func TestClaimsInterceptors(t *testing.T) {
+	var claims IClaims
+	conn := startTestGrpcServer(t, func(ctx context.Context) error {
+		claims, _ = ClaimsFromContext(ctx)
+		return nil
+	}, grpc.ChainUnaryInterceptor(UnaryErrorInterceptor(), UnaryClaimsInterceptor(testTokenVerifier{}, testGrpcMethodLogin)),
+		grpc.ChainStreamInterceptor(StreamErrorInterceptor(), StreamClaimsInterceptor(testTokenVerifier{})))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		code   codes.Code
+		reason string
+		id     int64
+	}{
+		{"valid token", testGrpcMethodGet, testGrpcValidToken, codes.OK, "", 42},
+		{"missing token", testGrpcMethodGet, "", codes.Unauthenticated, "", 0},
+		{"expired token", testGrpcMethodGet, testGrpcExpiredToken, codes.Unauthenticated, ERROR_JWT_EXPIRED.Error(), 0},
+		{"invalid token", testGrpcMethodGet, "forged", codes.Unauthenticated, ERROR_JWT_SIGNATURE.Error(), 0},
+		{"public method", testGrpcMethodLogin, "", codes.OK, "", 0},
+		{"public method with token", testGrpcMethodLogin, testGrpcValidToken, codes.OK, "", 42},
+		{"stream", testGrpcMethodWatch, testGrpcValidToken, codes.OK, "", 42},
+		{"stream without token", testGrpcMethodWatch, "", codes.Unauthenticated, "", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims = nil
+
+			var err error
+			if test.method == testGrpcMethodWatch {
+				var stream grpc.ClientStream
+				stream, err = conn.NewStream(testGrpcContext(test.token), &grpc.StreamDesc{ServerStreams: true}, test.method)
+				if err == nil {
+					stream.CloseSend()
+					err = stream.RecvMsg(&emptypb.Empty{})
+				}
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+			} else {
+				err = conn.Invoke(testGrpcContext(test.token), test.method, &emptypb.Empty{}, &emptypb.Empty{})
+			}
+
+			e, _ := status.FromError(err)
+			if e.Code() != test.code {
+				t.Fatalf("status is %v, expected %v", e, test.code)
+			}
+			if test.reason != "" && (grpcErrorInfoOf(e) == nil || grpcErrorInfoOf(e).Reason != test.reason) {
+				t.Errorf("error info of %v is %v, expected %s", e, grpcErrorInfoOf(e), test.reason)
+			}
+
+			if test.id == 0 {
+				if claims != nil {
+					t.Errorf("claims are %+v, expected none", claims)
+				}
+				return
+			}
+			if result, ok := claims.(*testClaims); !ok || result.GetId() != test.id || result.TenantId != 7 {
+				t.Errorf("claims are %+v", claims)
+			}
+		})
+	}
+}
+
+func TestErrorInterceptors(t *testing.T) {
+	var handled error
+	conn := startTestGrpcServer(t, func(ctx context.Context) error {
+		return handled
+	}, grpc.UnaryInterceptor(UnaryErrorInterceptor()), grpc.StreamInterceptor(StreamErrorInterceptor()))
+
+	sqlErr := &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "208", Message: "Invalid object name 'dbo.SecretOrders'."}
+	uniqueErr := &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23505", Message: `duplicate key value violates unique constraint "uq_orders_number"`}
+
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		reason  string
+		message string
+	}{
+		{"unclassified sql error", errors.Join(errors.New("query failed"), sqlErr), codes.Internal, ERROR_CODE_SQL, "A database error occurred."},
+		{"classified sql error", uniqueErr, codes.AlreadyExists, ERROR_SQL_UNIQUE_VIOLATION.Error(), ""},
+		{"coded error", ERROR_JWT_EXPIRED, codes.Unauthenticated, ERROR_JWT_EXPIRED.Error(), ""},
+		{"unknown error", errors.New("dial tcp 10.0.0.5:1433"), codes.Unknown, ERROR_CODE_UNKNOWN, "An unexpected error occurred."},
+		{"status error", status.Error(codes.NotFound, "order not found"), codes.NotFound, "", "order not found"},
+	}
+
+	for _, test := range tests {
+		handled = test.err
+		for _, method := range []string{testGrpcMethodGet, testGrpcMethodWatch} {
+			var err error
+			if method == testGrpcMethodWatch {
+				var stream grpc.ClientStream
+				if stream, err = conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, method); err == nil {
+					err = stream.RecvMsg(&emptypb.Empty{})
+				}
+			} else {
+				err = conn.Invoke(context.Background(), method, &emptypb.Empty{}, &emptypb.Empty{})
+			}
+
+			e, _ := status.FromError(err)
+			if e.Code() != test.code || (test.message != "" && e.Message() != test.message) {
+				t.Errorf("%s: status of %s is %v", test.name, method, e)
+			}
+			if info := grpcErrorInfoOf(e); test.reason != "" && (info == nil || info.Reason != test.reason) {
+				t.Errorf("%s: error info of %s is %v, expected %s", test.name, method, info, test.reason)
+			}
+			if strings.Contains(e.Message(), "SecretOrders") || strings.Contains(e.Message(), "10.0.0.5") {
+				t.Errorf("%s: status of %s leaks the error: %v", test.name, method, e)
+			}
+		}
+	}
+
+	handled = sqlErr
+	err := conn.Invoke(context.Background(), testGrpcMethodGet, &emptypb.Empty{}, &emptypb.Empty{})
+	if e, _ := status.FromError(err); len(grpcErrorInfoOf(e).GetMetadata()) != 0 {
+		t.Errorf("error info of an unclassified SQL error is %v", grpcErrorInfoOf(e))
+	}
+}
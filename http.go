@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	HTTP_CONTENT_TYPE_PROBLEM string = "application/problem+json"
+	HTTP_PROBLEM_TYPE_DEFAULT string = "about:blank"
+
+	// HTTP_STATUS_CLIENT_CLOSED_REQUEST is the non-standard status of a canceled request.
+	HTTP_STATUS_CLIENT_CLOSED_REQUEST int = 499
+)
+
+// GRPC_HTTP_STATUSES maps the gRPC codes to the HTTP statuses, as the gRPC gateway does.
+var GRPC_HTTP_STATUSES = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           HTTP_STATUS_CLIENT_CLOSED_REQUEST,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// GrpcCodeToHttpStatus returns the HTTP status of a gRPC code, or 500 for an unknown code.
+func GrpcCodeToHttpStatus(code codes.Code) int {
+	if result, ok := GRPC_HTTP_STATUSES[code]; ok {
+		return result
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem is the JSON document written for an error, following RFC 7807.
+// Code is the code of the error, e.g. "888" for a validation error, and Field the invalid field, if any.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Field    string `json:"field,omitempty"`
+
+	// Violations lists the invalid fields of a validation error.
+	Violations []FieldViolation `json:"violations,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// NewProblem converts an error into a Problem, with the messages in ERROR_LANGUAGE_DEFAULT, see NewLocalizedProblem.
+func NewProblem(err error) *Problem {
+	return NewLocalizedProblem(err, "")
+}
+
+// NewLocalizedProblem converts an error into a Problem, with the messages in the given language:
+//   - IValidateError keeps its status, code, field and violations
+//   - gRPC status errors get the HTTP status of their code, see GRPC_HTTP_STATUSES,
+//     and an InvalidArgument status becomes a validation error, as in HandleGrpcError
+//   - the other errors are converted by ToGrpcError first, so unclassified SQL errors become 500 with the message of ERROR_CODE_SQL,
+//     ERROR_ACTION_NOT_FOUND becomes 501 and the ERROR_JWT_* errors become 401
+//
+// When the error has a registered ErrorCode, the status is its HTTP status and the detail its localized message.
+// Otherwise the code is ERROR_CODE_UNKNOWN and the detail its localized message, so internal messages never reach the client,
+// and a status without ErrorInfo has no code and the title of its HTTP status as detail.
+func NewLocalizedProblem(err error, language string) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	var validate IValidateError
+	if errors.As(err, &validate) {
+		return newValidateProblem(validate, language)
+	}
+
+	e, ok := status.FromError(err)
+	if !ok {
+		e, _ = status.FromError(ToGrpcError(err))
+	}
+
+	if e.Code() == codes.InvalidArgument {
+		if validate, ok := NewValidateHttpError(e.Err()).(IValidateError); ok {
+			return newValidateProblem(validate, language)
+		}
+	}
+
+	info := grpcErrorInfoOf(e)
+	if info == nil {
+		// The message of a status without ErrorInfo, e.g. from another service, is not shown to the client either.
+		result := newProblem(GrpcCodeToHttpStatus(e.Code()), "", "", "")
+		result.Detail = result.Title
+		return result
+	}
+
+	if code := FindErrorCode(info.Reason); code != nil {
+		return newProblem(IIF(code.HttpStatus == 0, GrpcCodeToHttpStatus(e.Code()), code.HttpStatus), code.Code, "", LocalizeErrorLanguage(HandleGrpcError(e.Err()), language))
+	}
+
+	// A reason not registered here, e.g. from another service, is not shown to the client.
+	return newProblem(GrpcCodeToHttpStatus(e.Code()), ERROR_CODE_UNKNOWN, "", unknownErrorMessage(language))
+}
+
+// WriteProblem writes the Problem of an error as the response, with the path of the request as instance,
+// and the messages in the language of the claims of the request context, see ClaimsFromContext.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := *NewLocalizedProblem(err, requestLanguage(r))
+	if problem.Instance == "" && r != nil && r.URL != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", HTTP_CONTENT_TYPE_PROBLEM)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(&problem)
+}
+
+// requestLanguage returns the language of the claims of the request context, or "" without claims.
+func requestLanguage(r *http.Request) string {
+	if r != nil {
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			return claims.GetLanguage()
+		}
+	}
+	return ""
+}
+
+// ErrorResponseWriter is the http.ResponseWriter given by ErrorMiddleware to the handlers,
+// which write their errors with WriteError.
+type ErrorResponseWriter struct {
+	http.ResponseWriter
+	request *http.Request
+	status  int
+	err     error
+}
+
+// NewErrorResponseWriter returns an ErrorResponseWriter writing the problems of the request into w.
+func NewErrorResponseWriter(w http.ResponseWriter, r *http.Request) *ErrorResponseWriter {
+	return &ErrorResponseWriter{ResponseWriter: w, request: r}
+}
+
+func (w *ErrorResponseWriter) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *ErrorResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteError writes the Problem of an error as the response.
+// The error is only recorded when the response has already started.
+func (w *ErrorResponseWriter) WriteError(err error) {
+	if err == nil {
+		return
+	}
+
+	w.err = err
+	if w.status != 0 {
+		return
+	}
+
+	problem := NewLocalizedProblem(err, requestLanguage(w.request))
+	w.status = problem.Status
+	WriteProblem(w.ResponseWriter, w.request, problem)
+}
+
+// Status returns the status written, or 0 if the response has not started.
+func (w *ErrorResponseWriter) Status() int {
+	return w.status
+}
+
+// Err returns the last error given to WriteError.
+func (w *ErrorResponseWriter) Err() error {
+	return w.err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (w *ErrorResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WriteError writes the Problem of an error as the response,
+// through the ErrorResponseWriter of ErrorMiddleware when w is one.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if e, ok := w.(*ErrorResponseWriter); ok {
+		e.WriteError(err)
+		return
+	}
+	WriteProblem(w, r, err)
+}
+
+// HttpHandlerFunc is an http.Handler returning its error, written as a Problem.
+type HttpHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HttpHandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// ErrorMiddleware gives an ErrorResponseWriter to the next handler, and writes its panics as a 500 Problem.
+func ErrorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := NewErrorResponseWriter(w, r)
+
+		defer func() {
+			if e := recover(); e != nil {
+				if e == http.ErrAbortHandler {
+					panic(e)
+				}
+				writer.WriteError(status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError)))
+			}
+		}()
+
+		next.ServeHTTP(writer, r)
+	})
+}
+
+func newProblem(statusCode int, code string, field string, detail string) *Problem {
+	title := http.StatusText(statusCode)
+	if statusCode == HTTP_STATUS_CLIENT_CLOSED_REQUEST {
+		title = "Client Closed Request"
+	}
+
+	return &Problem{
+		Type:   HTTP_PROBLEM_TYPE_DEFAULT,
+		Title:  title,
+		Status: statusCode,
+		Detail: detail,
+		Code:   code,
+		Field:  field,
+	}
+}
+
+// newValidateProblem returns the Problem of a validation error,
+// whose violations without message get the localized message of their code, when it is registered.
+func newValidateProblem(err IValidateError, language string) *Problem {
+	result := newProblem(err.Status(), err.Error(), err.Field(), LocalizeErrorLanguage(err, language))
+
+	for _, e := range ValidateErrorViolations(err) {
+		if code := FindErrorCode(e.Code); e.Message == "" && code != nil {
+			params := map[string]string{VALIDATE_METADATA_FIELD: e.Field}
+			for k, v := range e.Params {
+				params[k] = v
+			}
+			e.Message = code.Message(language, params)
+		}
+		result.Violations = append(result.Violations, e)
+	}
+
+	return result
+}
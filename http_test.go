@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorMiddlewareLocalizesProblem(t *testing.T) {
+	previous := FindErrorCode("test_order_closed")
+	t.Cleanup(func() {
+		if previous == nil {
+			UnregisterErrorCode("test_order_closed")
+		} else {
+			RegisterErrorCode(previous)
+		}
+	})
+	RegisterErrorCode(&ErrorCode{Code: "test_order_closed", HttpStatus: http.StatusConflict, GrpcCode: codes.FailedPrecondition,
+		Severity: ERROR_SEVERITY_WARNING, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: "The order {id} is closed.", "vi": "Đơn hàng {id} đã đóng."}})
+
+	handler := ErrorMiddleware(HttpHandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return NewCodedError("test_order_closed", map[string]string{"id": "7"})
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	request = request.WithContext(ContextWithClaims(request.Context(), &Claims{Language: "vi-VN"}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	var problem Problem
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusConflict || problem.Code != "test_order_closed" || problem.Detail != "Đơn hàng 7 đã đóng." {
+		t.Errorf("problem is %d %+v", recorder.Code, problem)
+	}
+}
+
+func TestNewProblemHidesUnknownErrors(t *testing.T) {
+	err := errors.New("dial tcp 10.0.0.5:1433: login failed for user sa")
+
+	problem := NewProblem(err)
+	if problem.Status != http.StatusInternalServerError || problem.Code != ERROR_CODE_UNKNOWN || strings.Contains(problem.Detail, "10.0.0.5") {
+		t.Errorf("problem is %+v", problem)
+	}
+
+	if e, _ := status.FromError(ToGrpcError(err)); strings.Contains(e.Message(), "10.0.0.5") || grpcErrorInfoOf(e).Reason != ERROR_CODE_UNKNOWN {
+		t.Errorf("status is %v", e)
+	}
+
+	problem = NewProblem(status.Error(codes.Unavailable, "dial tcp 10.0.0.5:50051: connection refused"))
+	if problem.Status != http.StatusServiceUnavailable || problem.Detail != http.StatusText(http.StatusServiceUnavailable) {
+		t.Errorf("problem of a status without error info is %+v", problem)
+	}
+}
@@ -0,0 +1,409 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JWT_ALGORITHM_HS256 string = "HS256"
+	JWT_ALGORITHM_RS256 string = "RS256"
+	JWT_ALGORITHM_ES256 string = "ES256"
+)
+
+var (
+	ERROR_JWT_MALFORMED     = errors.New("jwt_malformed")
+	ERROR_JWT_ALGORITHM     = errors.New("jwt_unsupported_algorithm")
+	ERROR_JWT_KEY_NOT_FOUND = errors.New("jwt_key_not_found")
+	ERROR_JWT_SIGNATURE     = errors.New("jwt_invalid_signature")
+	ERROR_JWT_EXPIRED       = errors.New("jwt_expired")
+	ERROR_JWT_NOT_YET_VALID = errors.New("jwt_not_yet_valid")
+	ERROR_JWT_ISSUER        = errors.New("jwt_invalid_issuer")
+	ERROR_JWT_AUDIENCE      = errors.New("jwt_invalid_audience")
+)
+
+// JwtClaimNames maps the fields of Claims to the names of the claims in the token.
+type JwtClaimNames struct {
+	Id             string
+	ClientId       string
+	UnitId         string
+	Username       string
+	Email          string
+	Fullname       string
+	Phone          string
+	IsAdmin        string
+	IsSystem       string
+	Language       string
+	IsBaseLanguage string
+}
+
+// DEFAULT_JWT_CLAIM_NAMES is the JwtClaimNames of a new JwtVerifier.
+var DEFAULT_JWT_CLAIM_NAMES = JwtClaimNames{
+	Id:             "id",
+	ClientId:       "clientId",
+	UnitId:         "unitId",
+	Username:       "username",
+	Email:          "email",
+	Fullname:       "fullName",
+	Phone:          "phone",
+	IsAdmin:        "isAdmin",
+	IsSystem:       "isSystem",
+	Language:       "language",
+	IsBaseLanguage: "isBaseLanguage",
+}
+
+// JwtKey is a key verifying the tokens signed with one algorithm.
+// Key is a []byte secret for HS256, a *rsa.PublicKey for RS256 and an *ecdsa.PublicKey on the P-256 curve for ES256.
+type JwtKey struct {
+	Id        string
+	Algorithm string
+	Key       interface{}
+}
+
+// NewHmacKey returns the HS256 key of a secret.
+func NewHmacKey(id string, secret []byte) *JwtKey {
+	return &JwtKey{Id: id, Algorithm: JWT_ALGORITHM_HS256, Key: secret}
+}
+
+// NewRsaKey returns the RS256 key of an RSA public key.
+func NewRsaKey(id string, key *rsa.PublicKey) *JwtKey {
+	return &JwtKey{Id: id, Algorithm: JWT_ALGORITHM_RS256, Key: key}
+}
+
+// NewEcdsaKey returns the ES256 key of an ECDSA public key.
+func NewEcdsaKey(id string, key *ecdsa.PublicKey) *JwtKey {
+	return &JwtKey{Id: id, Algorithm: JWT_ALGORITHM_ES256, Key: key}
+}
+
+// ParsePublicKeyPem returns the JwtKey of a PEM encoded public key, RSA (RS256) or ECDSA (ES256).
+func ParsePublicKeyPem(id string, data []byte) (*JwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt: no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return NewRsaKey(id, k), nil
+	case *ecdsa.PublicKey:
+		return NewEcdsaKey(id, k), nil
+	default:
+		return nil, fmt.Errorf("jwt: unsupported public key %T", key)
+	}
+}
+
+// JwtKeySet holds the keys verifying tokens, looked up by the "kid" header of the token.
+// Keys can be added and removed while tokens are verified, to rotate them.
+type JwtKeySet struct {
+	mutex sync.RWMutex
+	keys  []*JwtKey
+}
+
+// NewJwtKeySet returns a new instance of JwtKeySet holding the given keys.
+func NewJwtKeySet(keys ...*JwtKey) *JwtKeySet {
+	result := &JwtKeySet{}
+	for _, e := range keys {
+		result.Add(e)
+	}
+	return result
+}
+
+// Add adds a key to the set, replacing the key with the same id.
+func (s *JwtKeySet) Add(key *JwtKey) {
+	if key == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = Where(s.keys, func(e *JwtKey) bool { return e.Id != key.Id })
+	s.keys = append(s.keys, key)
+}
+
+// Remove removes the key with the given id from the set.
+func (s *JwtKeySet) Remove(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = Where(s.keys, func(e *JwtKey) bool { return e.Id != id })
+}
+
+// Find returns the keys able to verify a token signed with an algorithm:
+// the key with the given id, or every key of the algorithm when the token has no "kid".
+func (s *JwtKeySet) Find(id string, algorithm string) []*JwtKey {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return Where(s.keys, func(e *JwtKey) bool {
+		return e.Algorithm == algorithm && (id == "" || e.Id == id)
+	})
+}
+
+// JwtVerifier verifies signed tokens and reads their claims.
+type JwtVerifier struct {
+	Keys *JwtKeySet
+	// Issuer, when set, must be the "iss" claim of the token.
+	Issuer string
+	// Audiences, when set, must contain one of the "aud" claims of the token.
+	Audiences []string
+	// Leeway is the clock skew tolerated when checking "exp" and "nbf".
+	Leeway time.Duration
+	// RequireExpiry rejects the tokens without "exp".
+	RequireExpiry bool
+	ClaimNames    JwtClaimNames
+	// Now returns the current time, time.Now by default.
+	Now func() time.Time
+}
+
+// NewJwtVerifier returns a new instance of JwtVerifier using the given keys,
+// requiring an expiry and reading the claims with DEFAULT_JWT_CLAIM_NAMES.
+func NewJwtVerifier(keys *JwtKeySet) *JwtVerifier {
+	return &JwtVerifier{
+		Keys:          keys,
+		Leeway:        time.Minute,
+		RequireExpiry: true,
+		ClaimNames:    DEFAULT_JWT_CLAIM_NAMES,
+		Now:           time.Now,
+	}
+}
+
+// Verify returns the claims of a token checked by VerifyClaims, implementing ITokenVerifier.
+func (v *JwtVerifier) Verify(token string) (IClaims, error) {
+	claims, err := v.VerifyClaims(token)
+	if err != nil {
+		// A nil *Claims would be a non-nil IClaims.
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyClaims checks the signature, the validity period, the issuer and the audience of a token,
+// and returns its claims. The errors wrap the ERROR_JWT_* values.
+func (v *JwtVerifier) VerifyClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ERROR_JWT_MALFORMED
+	}
+
+	header := struct {
+		Algorithm string `json:"alg"`
+		KeyId     string `json:"kid"`
+	}{}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %s", ERROR_JWT_MALFORMED, err.Error())
+	}
+
+	if !ComparableContains(header.Algorithm, JWT_ALGORITHM_HS256, JWT_ALGORITHM_RS256, JWT_ALGORITHM_ES256) {
+		return nil, fmt.Errorf("%w: %q", ERROR_JWT_ALGORITHM, header.Algorithm)
+	}
+
+	keys := v.Keys.Find(header.KeyId, header.Algorithm)
+	if len(keys) < 1 {
+		return nil, fmt.Errorf("%w: kid %q, alg %s", ERROR_JWT_KEY_NOT_FOUND, header.KeyId, header.Algorithm)
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if Find(keys, func(key *JwtKey) bool { return verifyJwtSignature(key, signed, signature) }) == nil {
+		return nil, ERROR_JWT_SIGNATURE
+	}
+
+	values := map[string]interface{}{}
+	if err := decodeJwtPart(parts[1], &values); err != nil {
+		return nil, err
+	}
+
+	result := v.mapClaims(values)
+	if err := v.validate(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validate checks the validity period, the issuer and the audience of the claims.
+func (v *JwtVerifier) validate(claims *Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if claims.ExpiresAt == nil && v.RequireExpiry {
+		return fmt.Errorf("%w: no expiry", ERROR_JWT_EXPIRED)
+	}
+
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(v.Leeway)) {
+		return ERROR_JWT_EXPIRED
+	}
+
+	if claims.NotBefore != nil && now.Add(v.Leeway).Before(*claims.NotBefore) {
+		return ERROR_JWT_NOT_YET_VALID
+	}
+
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return fmt.Errorf("%w: %q", ERROR_JWT_ISSUER, claims.Issuer)
+	}
+
+	if len(v.Audiences) > 0 && Find(claims.Audience, func(aud string) bool { return ComparableContains(aud, v.Audiences...) }) == "" {
+		return fmt.Errorf("%w: %v", ERROR_JWT_AUDIENCE, claims.Audience)
+	}
+
+	return nil
+}
+
+// mapClaims reads the registered claims and the claims named by ClaimNames.
+func (v *JwtVerifier) mapClaims(values map[string]interface{}) *Claims {
+	names := v.ClaimNames
+	return &Claims{
+		Id:             jwtInt(values[names.Id]),
+		ClientId:       jwtInt(values[names.ClientId]),
+		UnitId:         jwtInt(values[names.UnitId]),
+		Username:       jwtString(values[names.Username]),
+		Email:          jwtString(values[names.Email]),
+		Fullname:       jwtString(values[names.Fullname]),
+		Phone:          jwtString(values[names.Phone]),
+		IsAdmin:        jwtBool(values[names.IsAdmin]),
+		IsSystem:       jwtBool(values[names.IsSystem]),
+		Language:       jwtString(values[names.Language]),
+		IsBaseLanguage: jwtBool(values[names.IsBaseLanguage]),
+		Subject:        jwtString(values["sub"]),
+		Issuer:         jwtString(values["iss"]),
+		Audience:       jwtStrings(values["aud"]),
+		ExpiresAt:      jwtTime(values["exp"]),
+		NotBefore:      jwtTime(values["nbf"]),
+		IssuedAt:       jwtTime(values["iat"]),
+		Values:         values,
+	}
+}
+
+// decodeJwtPart decodes a base64url JSON part of a token, keeping its numbers as json.Number.
+func decodeJwtPart(part string, result interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ERROR_JWT_MALFORMED, err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(result); err != nil {
+		return fmt.Errorf("%w: %s", ERROR_JWT_MALFORMED, err.Error())
+	}
+
+	return nil
+}
+
+// verifyJwtSignature reports whether the signature of the signed part of a token matches the key.
+func verifyJwtSignature(key *JwtKey, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		return key.Algorithm == JWT_ALGORITHM_HS256 && hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		return key.Algorithm == JWT_ALGORITHM_RS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if key.Algorithm != JWT_ALGORITHM_ES256 || k.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func jwtInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return int64(f)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	default:
+		return 0
+	}
+}
+
+func jwtString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func jwtBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	case json.Number:
+		return jwtInt(v) != 0
+	default:
+		return false
+	}
+}
+
+func jwtStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		return Select(v, jwtString)
+	default:
+		return nil
+	}
+}
+
+func jwtTime(value interface{}) *time.Time {
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil
+	}
+
+	seconds, err := number.Float64()
+	if err != nil {
+		return nil
+	}
+
+	result := time.Unix(0, int64(seconds*float64(time.Second)))
+	return &result
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJwtVerifierVerify(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	verifier := NewJwtVerifier(NewJwtKeySet(NewHmacKey("h1", secret), NewRsaKey("r1", &rsaKey.PublicKey), NewEcdsaKey("e1", &ecdsaKey.PublicKey)))
+	verifier.Issuer = "issuer"
+	verifier.Audiences = []string{"api"}
+	verifier.Now = func() time.Time { return now }
+
+	valid := map[string]interface{}{"id": 42, "username": "jdoe", "isAdmin": true, "language": "vi",
+		"iss": "issuer", "aud": []string{"web", "api"}, "exp": now.Add(time.Hour).Unix()}
+	with := func(name string, value interface{}) map[string]interface{} {
+		result := map[string]interface{}{}
+		for k, v := range valid {
+			result[k] = v
+		}
+		if value == nil {
+			delete(result, name)
+		} else {
+			result[name] = value
+		}
+		return result
+	}
+
+	signHmac := func(header string, payload string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(header + "." + payload))
+		return mac.Sum(nil)
+	}
+	signRsa := func(header string, payload string) []byte {
+		digest := sha256.Sum256([]byte(header + "." + payload))
+		result, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		return result
+	}
+	signEcdsa := func(header string, payload string) []byte {
+		digest := sha256.Sum256([]byte(header + "." + payload))
+		r, s, _ := ecdsa.Sign(rand.Reader, ecdsaKey, digest[:])
+		result := make([]byte, 64)
+		r.FillBytes(result[:32])
+		s.FillBytes(result[32:])
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		alg      string
+		kid      string
+		sign     func(string, string) []byte
+		payload  map[string]interface{}
+		expected error
+	}{
+		{"hs256", JWT_ALGORITHM_HS256, "h1", signHmac, valid, nil},
+		{"rs256", JWT_ALGORITHM_RS256, "r1", signRsa, valid, nil},
+		{"es256", JWT_ALGORITHM_ES256, "", signEcdsa, valid, nil},
+		{"none algorithm", "none", "", signHmac, valid, ERROR_JWT_ALGORITHM},
+		{"unknown key", JWT_ALGORITHM_HS256, "h2", signHmac, valid, ERROR_JWT_KEY_NOT_FOUND},
+		{"algorithm of another key", JWT_ALGORITHM_HS256, "r1", signHmac, valid, ERROR_JWT_KEY_NOT_FOUND},
+		{"bad signature", JWT_ALGORITHM_RS256, "r1", signHmac, valid, ERROR_JWT_SIGNATURE},
+		{"expired", JWT_ALGORITHM_HS256, "h1", signHmac, with("exp", now.Add(-2*time.Minute).Unix()), ERROR_JWT_EXPIRED},
+		{"expired within leeway", JWT_ALGORITHM_HS256, "h1", signHmac, with("exp", now.Add(-30*time.Second).Unix()), nil},
+		{"no expiry", JWT_ALGORITHM_HS256, "h1", signHmac, with("exp", nil), ERROR_JWT_EXPIRED},
+		{"not yet valid", JWT_ALGORITHM_HS256, "h1", signHmac, with("nbf", now.Add(time.Hour).Unix()), ERROR_JWT_NOT_YET_VALID},
+		{"other issuer", JWT_ALGORITHM_HS256, "h1", signHmac, with("iss", "other"), ERROR_JWT_ISSUER},
+		{"other audience", JWT_ALGORITHM_HS256, "h1", signHmac, with("aud", "mobile"), ERROR_JWT_AUDIENCE},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := encodeTestJwtPart(t, map[string]string{"alg": test.alg, "kid": test.kid, "typ": "JWT"})
+			payload := encodeTestJwtPart(t, test.payload)
+			token := header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(test.sign(header, payload))
+
+			claims, err := verifier.VerifyClaims(token)
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("error is %v, expected %v", err, test.expected)
+			}
+			if test.expected == nil && (claims.Id != 42 || claims.Username != "jdoe" || !claims.IsAdmin || claims.Language != "vi") {
+				t.Errorf("claims are %+v", claims)
+			}
+		})
+	}
+
+	if _, err := verifier.Verify("not.a-token"); !errors.Is(err, ERROR_JWT_MALFORMED) {
+		t.Errorf("error of a malformed token is %v", err)
+	}
+}
+
+func encodeTestJwtPart(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -1,55 +1,324 @@
 package utils
 
-import "reflect"
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
 
 type Finalware = func()
 
+// The modes of a listener, chosen at construction.
+const (
+	// LISTENER_MODE_ONCE invokes the events on the first Invoke only, for good. It is the default mode.
+	LISTENER_MODE_ONCE string = "once"
+	// LISTENER_MODE_EVERY_TIME invokes the events on every Invoke, for recurring events.
+	LISTENER_MODE_EVERY_TIME string = "every-time"
+	// LISTENER_MODE_RESETTABLE invokes the events on the first Invoke, until Reset is called.
+	LISTENER_MODE_RESETTABLE string = "resettable"
+)
+
+// Listener is an event listener for untyped events.
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
 type Listener struct {
-	events    []func(args ...interface{})
-	isInvoked bool
+	listenerEvents[func(args ...interface{}) error]
 }
 
-// NewListenerV1 returns a new instance of ListenerV1 with initial values of p1 and p2.
+// NewListener returns a new instance of Listener, invoked once.
 func NewListener() *Listener {
 	return &Listener{}
 }
 
+// NewListenerWithMode returns a new instance of Listener with the given LISTENER_MODE_*.
+func NewListenerWithMode(mode string) *Listener {
+	result := &Listener{}
+	result.mode = mode
+	return result
+}
+
 func (l *Listener) Push(event func(args ...interface{})) {
 	if event == nil {
 		return
 	}
 
-	l.events = append(l.events, event)
+	l.push(&event, func(args ...interface{}) error {
+		event(args...)
+		return nil
+	}, false, 0)
 }
 
+// Pop removes all the events pushed with the same function.
+// Closures created by the same literal share their code, so they are better removed with Subscribe.
 func (l *Listener) Pop(event func(args ...interface{})) {
 	if event == nil {
 		return
 	}
 
 	eventPtr := reflect.ValueOf(event).Pointer()
-	for i := 0; i < len(l.events); i++ {
-		itemPtr := reflect.ValueOf(l.events[i]).Pointer()
-		if itemPtr == eventPtr {
-			l.removeAt(i)
-			i--
-		}
+	l.pop(func(key any) bool {
+		return reflect.ValueOf(key).Elem().Pointer() == eventPtr
+	}, true)
+}
+
+// Subscribe adds an event to the listener, removed by the Unsubscribe of the returned subscription.
+func (l *Listener) Subscribe(event func(args ...interface{})) ISubscription {
+	return l.SubscribeContext(context.Background(), event)
+}
+
+// SubscribeContext adds an event to the listener until the context is done, or the subscription unsubscribed.
+func (l *Listener) SubscribeContext(ctx context.Context, event func(args ...interface{})) ISubscription {
+	if event == nil {
+		return newEmptySubscription()
 	}
+
+	return l.subscribe(ctx, &event, func(args ...interface{}) error {
+		event(args...)
+		return nil
+	}, 0)
+}
+
+// SubscribeError adds an event returning an error, joined to the errors returned by InvokeErr.
+func (l *Listener) SubscribeError(event func(args ...interface{}) error) ISubscription {
+	return l.SubscribeErrorContext(context.Background(), event)
+}
+
+// SubscribeErrorContext adds an event returning an error until the context is done, or the subscription unsubscribed.
+func (l *Listener) SubscribeErrorContext(ctx context.Context, event func(args ...interface{}) error) ISubscription {
+	if event == nil {
+		return newEmptySubscription()
+	}
+
+	return l.subscribe(ctx, &event, event, 0)
 }
 
+// Invoke calls all the events, as InvokeErr does, dropping their errors.
 func (l *Listener) Invoke(args ...interface{}) {
-	if l.isInvoked {
-		return
+	l.InvokeErr(args...)
+}
+
+// InvokeErr calls all the events, and returns their errors joined, a panicking event returning a PanicError.
+func (l *Listener) InvokeErr(args ...interface{}) error {
+	return l.invoke(func(event func(args ...interface{}) error) error {
+		return event(args...)
+	}, nil)
+}
+
+// InvokeAsync calls the events concurrently with the given options, and returns without waiting for them.
+func (l *Listener) InvokeAsync(options InvokeOptions, args ...interface{}) IInvokeResult {
+	return l.invokeAsync(options, func(event func(args ...interface{}) error) error {
+		return event(args...)
+	}, nil)
+}
+
+// listenerEvent is an event of a listener: the key identifying it for Pop and Unsubscribe,
+// i.e. the pointer given to Push or created by Subscribe, the handler calling it, and its priority.
+type listenerEvent[H any] struct {
+	key      any
+	handler  H
+	priority int
+}
+
+// listenerEvents holds the events of a listener, safe for concurrent use.
+// Push and Pop replace the slice under the lock (copy-on-write), and Invoke calls a snapshot outside of it,
+// so an event can push or pop events of its own listener.
+// H is the handler of an event, returning an error, to which the events without error are adapted.
+type listenerEvents[H any] struct {
+	mutex     sync.RWMutex
+	events    []listenerEvent[H]
+	isInvoked atomic.Bool
+	mode      string
+
+	// middlewares wrap the handler of every event, the first one outermost, see use.
+	middlewares []func(H) H
+
+	// replays are the last invocations, called on the events pushed afterwards, see SetReplay.
+	replays    []func(H) error
+	replaySize int
+
+	// tails holds the last ordered InvokeAsync task of each event, see InvokeOptions.Ordered.
+	tails map[any]chan struct{}
+}
+
+// IsInvoked reports whether the listener has been invoked since its creation or its last Reset.
+func (l *listenerEvents[H]) IsInvoked() bool {
+	return l.isInvoked.Load()
+}
+
+// Reset lets a LISTENER_MODE_RESETTABLE listener be invoked again, and clears IsInvoked of a LISTENER_MODE_EVERY_TIME one,
+// both forgetting the invocations replayed to the events pushed afterwards.
+// It does nothing on a LISTENER_MODE_ONCE listener.
+func (l *listenerEvents[H]) Reset() {
+	if l.mode == LISTENER_MODE_RESETTABLE || l.mode == LISTENER_MODE_EVERY_TIME {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		l.replays = nil
+		l.isInvoked.Store(false)
 	}
+}
 
-	l.isInvoked = true
+// SetReplay sets the number of the last invocations of a LISTENER_MODE_EVERY_TIME listener
+// replayed to the events pushed afterwards, in their order, none by default.
+// A LISTENER_MODE_ONCE or LISTENER_MODE_RESETTABLE listener always replays its invocation, as a promise does,
+// so an event pushed once it has been invoked is called at once.
+func (l *listenerEvents[H]) SetReplay(size int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
-	for _, e := range l.events {
-		event := e
-		event(args...)
+	l.replaySize = IIF(size > 0, size, 0)
+	if len(l.replays) > l.replaySize {
+		l.replays = l.replays[len(l.replays)-l.replaySize:]
+	}
+}
+
+// push inserts an event after the events of the same or a higher priority,
+// unless unique is set and an event of the same key is already there.
+// The event is then called with the replayed invocations, if any, their errors being dropped.
+func (l *listenerEvents[H]) push(key any, handler H, unique bool, priority int) {
+	replays, handler := l.insert(key, handler, unique, priority)
+	for _, e := range replays {
+		call := e
+		recoverError(func() error {
+			return call(handler)
+		})
+	}
+}
+
+// insert inserts an event, and returns the invocations to replay with its handler wrapped by the middlewares.
+func (l *listenerEvents[H]) insert(key any, handler H, unique bool, priority int) ([]func(H) error, H) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if unique {
+		for _, e := range l.events {
+			if e.key == key {
+				return nil, handler
+			}
+		}
+	}
+
+	index := len(l.events)
+	for i, e := range l.events {
+		if e.priority < priority {
+			index = i
+			break
+		}
+	}
+
+	events := make([]listenerEvent[H], 0, len(l.events)+1)
+	events = append(events, l.events[:index]...)
+	events = append(events, listenerEvent[H]{key: key, handler: handler, priority: priority})
+	l.events = append(events, l.events[index:]...)
+
+	return l.replays, l.wrap(handler)
+}
+
+// use appends middlewares wrapping the handler of every event, including the events already pushed.
+func (l *listenerEvents[H]) use(middlewares ...func(H) H) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	result := make([]func(H) H, 0, len(l.middlewares)+len(middlewares))
+	result = append(result, l.middlewares...)
+	l.middlewares = append(result, middlewares...)
+}
+
+// pop removes the first event whose key is matching, or all of them.
+func (l *listenerEvents[H]) pop(match func(key any) bool, all bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	events := make([]listenerEvent[H], 0, len(l.events))
+	for i, e := range l.events {
+		if match(e.key) {
+			if !all {
+				events = append(events, l.events[i+1:]...)
+				break
+			}
+			continue
+		}
+		events = append(events, e)
+	}
+	l.events = events
+}
+
+// snapshot returns the events with their handlers wrapped by the middlewares, which are never modified afterwards,
+// and records the invocation to replay, under the same lock as push so that an event is either in the snapshot or replayed.
+func (l *listenerEvents[H]) snapshot(call func(H) error) []listenerEvent[H] {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch {
+	case l.mode != LISTENER_MODE_EVERY_TIME:
+		l.replays = []func(H) error{call}
+	case l.replaySize > 0:
+		replays := make([]func(H) error, 0, l.replaySize)
+		if len(l.replays) >= l.replaySize {
+			replays = append(replays, l.replays[len(l.replays)-l.replaySize+1:]...)
+		} else {
+			replays = append(replays, l.replays...)
+		}
+		l.replays = append(replays, call)
+	}
+
+	if len(l.middlewares) == 0 {
+		return l.events
+	}
+
+	result := make([]listenerEvent[H], len(l.events))
+	for i, e := range l.events {
+		e.handler = l.wrap(e.handler)
+		result[i] = e
+	}
+	return result
+}
+
+// wrap returns a handler wrapped by the middlewares, the mutex being locked.
+func (l *listenerEvents[H]) wrap(handler H) H {
+	for i := len(l.middlewares) - 1; i >= 0; i-- {
+		handler = l.middlewares[i](handler)
+	}
+	return handler
+}
+
+// tryInvoke sets the listener invoked, and reports whether its events must be called:
+// always for a LISTENER_MODE_EVERY_TIME listener, otherwise only for the one caller finding it not invoked yet.
+func (l *listenerEvents[H]) tryInvoke() bool {
+	if l.mode == LISTENER_MODE_EVERY_TIME {
+		l.isInvoked.Store(true)
+		return true
+	}
+	return l.isInvoked.CompareAndSwap(false, true)
+}
+
+// invoke calls the events of a snapshot one after the other, then the finalwares,
+// and returns their errors joined. A panic is recovered as a PanicError, so the next events and the finalwares still run.
+func (l *listenerEvents[H]) invoke(call func(H) error, finalwares []Finalware) error {
+	if !l.tryInvoke() {
+		return nil
 	}
+
+	var errs []error
+	for _, e := range l.snapshot(call) {
+		handler := e.handler
+		errs = append(errs, recoverError(func() error {
+			return call(handler)
+		}))
+	}
+	return errors.Join(append(errs, callFinalwares(finalwares)...)...)
 }
 
-func (l *Listener) removeAt(i int) {
-	l.events = RemoveAt(l.events, i)
+// callFinalwares calls the finalwares, each recovered from its panic, and returns their errors.
+func callFinalwares(finalwares []Finalware) []error {
+	result := make([]error, 0, len(finalwares))
+	for _, e := range finalwares {
+		finalware := e
+		result = append(result, recoverError(func() error {
+			finalware()
+			return nil
+		}))
+	}
+	return result
 }
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"errors"
+	"runtime"
+)
+
+// InvokeOptions sets how InvokeAsync dispatches the events of a listener.
+type InvokeOptions struct {
+	// MaxWorkers is the number of events run at the same time, runtime.GOMAXPROCS(0) when not positive.
+	MaxWorkers int
+	// Ordered makes each event receive the invocations in the order of the InvokeAsync calls,
+	// an event waiting for its previous invocation to return before running the next one.
+	Ordered bool
+}
+
+// IInvokeResult is the pending result of InvokeAsync.
+type IInvokeResult interface {
+	// Done is closed once all the events and finalwares have returned.
+	Done() <-chan struct{}
+	// Wait blocks until all the events and finalwares have returned, and returns their errors joined.
+	Wait() error
+	// Err returns the errors of the events and finalwares joined, once Done is closed.
+	Err() error
+}
+
+type invokeResult struct {
+	done chan struct{}
+	err  error
+}
+
+func newInvokeResult() *invokeResult {
+	return &invokeResult{done: make(chan struct{})}
+}
+
+func (r *invokeResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *invokeResult) Wait() error {
+	<-r.done
+	return r.err
+}
+
+func (r *invokeResult) Err() error {
+	select {
+	case <-r.done:
+		return r.err
+	default:
+		return nil
+	}
+}
+
+// invokeAsync calls the events of a snapshot with a bounded pool of RunFuncThreads, then the finalwares, in the background.
+// As for invoke, the errors and recovered panics are joined, here in the result.
+func (l *listenerEvents[H]) invokeAsync(options InvokeOptions, call func(H) error, finalwares []Finalware) IInvokeResult {
+	result := newInvokeResult()
+	if !l.tryInvoke() {
+		close(result.done)
+		return result
+	}
+
+	events := l.snapshot(call)
+	errs := make([]error, len(events))
+	tasks := make([]Function, len(events))
+	for i, e := range events {
+		handler, index := e.handler, i
+		tasks[i] = l.asyncTask(e.key, options.Ordered, func() {
+			errs[index] = recoverError(func() error {
+				return call(handler)
+			})
+		})
+	}
+
+	workers := options.MaxWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	go func() {
+		defer close(result.done)
+		RunFuncThreads(tasks, workers)
+		result.err = errors.Join(append(errs, callFinalwares(finalwares)...)...)
+	}()
+
+	return result
+}
+
+// asyncTask returns the task calling an event, identified by its key.
+// An ordered task waits for the previous ordered task of the same event, which runs in the pool of its own invocation.
+func (l *listenerEvents[H]) asyncTask(key any, ordered bool, call func()) Function {
+	if !ordered {
+		return call
+	}
+
+	done := make(chan struct{})
+
+	l.mutex.Lock()
+	if l.tails == nil {
+		l.tails = map[any]chan struct{}{}
+	}
+	previous := l.tails[key]
+	l.tails[key] = done
+	l.mutex.Unlock()
+
+	return func() {
+		defer func() {
+			l.mutex.Lock()
+			if l.tails[key] == done {
+				delete(l.tails, key)
+			}
+			l.mutex.Unlock()
+			close(done)
+		}()
+
+		if previous != nil {
+			<-previous
+		}
+		call()
+	}
+}
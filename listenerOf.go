@@ -0,0 +1,259 @@
+package utils
+
+import "context"
+
+// Args2 to Args6 are the tuples of the parameters of the ListenerV2 to ListenerV6 events, the argument of their ListenerOf.
+type Args2[P1 any, P2 any] struct {
+	A P1
+	B P2
+}
+
+type Args3[P1 any, P2 any, P3 any] struct {
+	A P1
+	B P2
+	C P3
+}
+
+type Args4[P1 any, P2 any, P3 any, P4 any] struct {
+	A P1
+	B P2
+	C P3
+	D P4
+}
+
+type Args5[P1 any, P2 any, P3 any, P4 any, P5 any] struct {
+	A P1
+	B P2
+	C P3
+	D P4
+	E P5
+}
+
+type Args6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] struct {
+	A P1
+	B P2
+	C P3
+	D P4
+	E P5
+	F P6
+}
+
+type FinalwareOf[A any] func(A)
+
+// MiddlewareOf wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareOf[A any] func(next func(A) error) func(A) error
+
+type IListenerOf[A any] interface {
+	Push(*func(A))
+	Pop(*func(A))
+	Invoke(A, ...FinalwareOf[A])
+	InvokeAll(A, ...Finalware)
+}
+
+// IEventListenerOf is the IListenerOf of ListenerOf, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerOf[A any] interface {
+	IListenerOf[A]
+	PushPriority(*func(A), int)
+	Subscribe(func(A)) ISubscription
+	SubscribeContext(context.Context, func(A)) ISubscription
+	SubscribePriority(func(A), int) ISubscription
+	SubscribeError(func(A) error) ISubscription
+	SubscribeErrorContext(context.Context, func(A) error) ISubscription
+	Use(...MiddlewareOf[A])
+	InvokeErr(A, ...FinalwareOf[A]) error
+	InvokeAllErr(A, ...Finalware) error
+	InvokeAsync(A, InvokeOptions, ...FinalwareOf[A]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerOf is the generic event listener of the events taking one argument, e.g. a tuple Args2 for several parameters.
+// ListenerV1 to ListenerV6 are built on the same listenerAdapter, so they all behave the same:
+// a pointer pushed again is ignored, Pop removes it, and Invoke calls the events by priority, then in push order.
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
+type ListenerOf[A any] struct {
+	listenerAdapter[A, func(A), func(A) error, argsOf[A]]
+}
+
+// NewListenerOf returns a new instance of ListenerOf, invoked once.
+func NewListenerOf[A any]() IEventListenerOf[A] {
+	return &ListenerOf[A]{}
+}
+
+// NewListenerOfWithMode returns a new instance of ListenerOf with the given LISTENER_MODE_*.
+func NewListenerOfWithMode[A any](mode string) IEventListenerOf[A] {
+	result := &ListenerOf[A]{}
+	result.mode = mode
+	return result
+}
+
+// Use adds middlewares wrapping the handler of every event, the first one outermost.
+// They apply to the events already pushed, and to the next Invoke calls.
+func (l *ListenerOf[A]) Use(middlewares ...MiddlewareOf[A]) {
+	l.use(Select(middlewares, argsOf[A]{}.middleware)...)
+}
+
+// Invoke calls all the events with the given argument, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerOf[A]) Invoke(args A, wares ...FinalwareOf[A]) {
+	l.InvokeErr(args, wares...)
+}
+
+// InvokeAll calls all the events with the given argument, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerOf[A]) InvokeAll(args A, wares ...Finalware) {
+	l.InvokeAllErr(args, wares...)
+}
+
+// InvokeErr calls all the events with the given argument, then the finalwares.
+// The errors of the events and finalwares are returned joined, a panic being recovered as a PanicError
+// so that the next events and the finalwares still run.
+func (l *ListenerOf[A]) InvokeErr(args A, wares ...FinalwareOf[A]) error {
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsOf[A]{}.finalware))
+}
+
+// InvokeAllErr calls all the events with the given argument, then the finalwares, as InvokeErr does.
+func (l *ListenerOf[A]) InvokeAllErr(args A, wares ...Finalware) error {
+	return l.invokeArgs(args, wares)
+}
+
+// InvokeAsync calls the events concurrently with the given options, then the finalwares once they all returned,
+// and returns without waiting for them.
+func (l *ListenerOf[A]) InvokeAsync(args A, options InvokeOptions, wares ...FinalwareOf[A]) IInvokeResult {
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsOf[A]{}.finalware))
+}
+
+// argsOf is the listenerArgs of ListenerOf, whose events already take the argument A.
+type argsOf[A any] struct{}
+
+func (argsOf[A]) event(event func(A)) func(A) error {
+	if event == nil {
+		return nil
+	}
+	return func(args A) error {
+		event(args)
+		return nil
+	}
+}
+
+func (argsOf[A]) errorEvent(event func(A) error) func(A) error {
+	return event
+}
+
+func (argsOf[A]) middleware(middleware MiddlewareOf[A]) func(func(A) error) func(A) error {
+	return middleware
+}
+
+func (argsOf[A]) finalware(ware FinalwareOf[A], args A) Finalware {
+	return func() {
+		ware(args)
+	}
+}
+
+// listenerArgs converts the events of a listener taking parameters, E without error and R returning one,
+// to the handlers of its listenerAdapter, taking their tuple A. A nil event is converted to nil.
+type listenerArgs[A any, E any, R any] interface {
+	event(E) func(A) error
+	errorEvent(R) func(A) error
+}
+
+// listenerAdapter implements the events of a listener on handlers taking the tuple A of its parameters:
+// E is the type of its events, R the type of its events returning an error, and C converts both to the handlers.
+// The listener implements the methods taking its parameters, middlewares and finalwares, which only convert them to A.
+type listenerAdapter[A any, E any, R any, C listenerArgs[A, E, R]] struct {
+	listenerEvents[func(A) error]
+}
+
+// Push adds an event to the listener, unless it is already there.
+func (l *listenerAdapter[A, E, R, C]) Push(event *E) {
+	l.PushPriority(event, 0)
+}
+
+// PushPriority adds an event called before the events of a lower priority,
+// and after the events of the same priority pushed before it.
+func (l *listenerAdapter[A, E, R, C]) PushPriority(event *E, priority int) {
+	if event == nil {
+		return
+	}
+
+	var converter C
+	l.push(event, func(args A) error {
+		return converter.event(*event)(args)
+	}, true, priority)
+}
+
+// Pop removes an event from the listener.
+func (l *listenerAdapter[A, E, R, C]) Pop(event *E) {
+	if event == nil {
+		return
+	}
+
+	l.pop(func(e any) bool {
+		return e == any(event)
+	}, true)
+}
+
+// Subscribe adds an event to the listener, removed by the Unsubscribe of the returned subscription,
+// so the caller does not need to keep a pointer to pop it.
+func (l *listenerAdapter[A, E, R, C]) Subscribe(event E) ISubscription {
+	return l.SubscribeContext(context.Background(), event)
+}
+
+// SubscribeContext adds an event to the listener until the context is done, or the subscription unsubscribed.
+func (l *listenerAdapter[A, E, R, C]) SubscribeContext(ctx context.Context, event E) ISubscription {
+	var converter C
+	return l.subscribeHandler(ctx, converter.event(event), 0)
+}
+
+// SubscribePriority adds an event with a priority, as PushPriority does, removed by the returned subscription.
+func (l *listenerAdapter[A, E, R, C]) SubscribePriority(event E, priority int) ISubscription {
+	var converter C
+	return l.subscribeHandler(context.Background(), converter.event(event), priority)
+}
+
+// SubscribeError adds an event returning an error, joined to the errors returned by InvokeErr.
+func (l *listenerAdapter[A, E, R, C]) SubscribeError(event R) ISubscription {
+	return l.SubscribeErrorContext(context.Background(), event)
+}
+
+// SubscribeErrorContext adds an event returning an error until the context is done, or the subscription unsubscribed.
+func (l *listenerAdapter[A, E, R, C]) SubscribeErrorContext(ctx context.Context, event R) ISubscription {
+	var converter C
+	return l.subscribeHandler(ctx, converter.errorEvent(event), 0)
+}
+
+// subscribeHandler pushes the handler of a subscription, under a key of its own.
+func (l *listenerAdapter[A, E, R, C]) subscribeHandler(ctx context.Context, handler func(A) error, priority int) ISubscription {
+	if handler == nil {
+		return newEmptySubscription()
+	}
+
+	return l.subscribe(ctx, &handler, handler, priority)
+}
+
+func (l *listenerAdapter[A, E, R, C]) invokeArgs(args A, finalwares []Finalware) error {
+	return l.invoke(func(handler func(A) error) error {
+		return handler(args)
+	}, finalwares)
+}
+
+func (l *listenerAdapter[A, E, R, C]) invokeArgsAsync(args A, options InvokeOptions, finalwares []Finalware) IInvokeResult {
+	return l.invokeAsync(options, func(handler func(A) error) error {
+		return handler(args)
+	}, finalwares)
+}
+
+// bindFinalwares returns the finalwares of a listener called with the tuple of an invocation.
+func bindFinalwares[A any, W any](args A, wares []W, bind func(W, A) Finalware) []Finalware {
+	return Select(wares, func(ware W) Finalware {
+		return bind(ware, args)
+	})
+}
+
+// adaptMiddleware returns the middleware of a listenerAdapter from the middleware of a listener taking parameters,
+// toHandler and fromHandler converting the handlers between the tuple A and the parameters.
+func adaptMiddleware[A any, H any](middleware func(H) H, toHandler func(func(A) error) H, fromHandler func(H) func(A) error) func(func(A) error) func(A) error {
+	return func(next func(A) error) func(A) error {
+		return fromHandler(middleware(toHandler(next)))
+	}
+}
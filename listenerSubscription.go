@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// ISubscription is an event pushed by the Subscribe method of a listener.
+type ISubscription interface {
+	// Unsubscribe removes the event from its listener. It can be called several times.
+	Unsubscribe()
+	// Done is closed once the event is removed, by Unsubscribe or by the end of the context of SubscribeContext.
+	Done() <-chan struct{}
+}
+
+type subscription struct {
+	once        sync.Once
+	unsubscribe func()
+	done        chan struct{}
+}
+
+func (s *subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.unsubscribe()
+		close(s.done)
+	})
+}
+
+func (s *subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// newEmptySubscription returns a subscription already unsubscribed, for a nil event.
+func newEmptySubscription() ISubscription {
+	result := &subscription{unsubscribe: func() {}, done: make(chan struct{})}
+	result.Unsubscribe()
+	return result
+}
+
+// subscribe pushes an event of a key created for it, and returns the subscription removing exactly this push,
+// which ends with the context.
+func (l *listenerEvents[H]) subscribe(ctx context.Context, key any, handler H, priority int) ISubscription {
+	result := &subscription{done: make(chan struct{})}
+	result.unsubscribe = func() {
+		l.pop(func(e any) bool {
+			return e == key
+		}, true)
+	}
+
+	if ctx.Err() != nil {
+		result.Unsubscribe()
+		return result
+	}
+
+	l.push(key, handler, false, priority)
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				result.Unsubscribe()
+			case <-result.done:
+			}
+		}()
+	}
+
+	return result
+}
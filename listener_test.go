@@ -0,0 +1,486 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListenerOnceCallsEachEventOnce(t *testing.T) {
+	listener := NewListenerV1[int]()
+	counts := make([]atomic.Int32, 64)
+
+	var wg sync.WaitGroup
+	for i := range counts {
+		count := &counts[i]
+		event := func(int) {
+			count.Add(1)
+		}
+
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			listener.Push(&event)
+		}()
+		go func() {
+			defer wg.Done()
+			listener.Invoke(1)
+		}()
+		go func() {
+			defer wg.Done()
+			listener.InvokeAsync(1, InvokeOptions{}).Wait()
+		}()
+	}
+	wg.Wait()
+
+	if !listener.IsInvoked() {
+		t.Fatal("listener is not invoked")
+	}
+	for i := range counts {
+		if count := counts[i].Load(); count != 1 {
+			t.Errorf("event %d is called %d times", i, count)
+		}
+	}
+}
+
+func TestListenerEveryTimeConcurrent(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+
+	var sum atomic.Int64
+	event := func(value int) {
+		sum.Add(int64(value))
+	}
+	listener.Push(&event)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		other := func(int) {}
+
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			listener.Push(&other)
+			listener.Pop(&other)
+		}()
+		go func() {
+			defer wg.Done()
+			listener.Subscribe(func(int) {}).Unsubscribe()
+		}()
+		go func() {
+			defer wg.Done()
+			listener.Invoke(1)
+		}()
+		go func() {
+			defer wg.Done()
+			listener.InvokeAsync(2, InvokeOptions{MaxWorkers: 2, Ordered: true}).Wait()
+		}()
+	}
+	wg.Wait()
+
+	if result := sum.Load(); result != 150 {
+		t.Errorf("sum is %d, expected 150", result)
+	}
+}
+
+func TestListenerInvokeJoinsErrorsAndPanics(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+	failure := errors.New("failure")
+
+	var calls []string
+	listener.SubscribeError(func(int) error {
+		calls = append(calls, "error")
+		return failure
+	})
+	listener.Subscribe(func(int) {
+		calls = append(calls, "panic")
+		panic("boom")
+	})
+	listener.SubscribePriority(func(int) {
+		calls = append(calls, "first")
+	}, 1)
+
+	err := listener.InvokeErr(1, func(int) {
+		calls = append(calls, "finalware")
+	})
+
+	var panicErr *PanicError
+	if !errors.Is(err, failure) || !errors.As(err, &panicErr) {
+		t.Errorf("error is %v", err)
+	}
+	if expected := []string{"first", "error", "panic", "finalware"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls are %v, expected %v", calls, expected)
+	}
+}
+
+func TestListenerV3MiddlewaresAndPop(t *testing.T) {
+	listener := NewListenerV3WithMode[string, int, bool](LISTENER_MODE_EVERY_TIME)
+
+	var calls []string
+	event := func(name string, value int, ok bool) {
+		calls = append(calls, name)
+	}
+	listener.Push(&event)
+	listener.Push(&event)
+	listener.Use(FilterV3(func(name string, value int, ok bool) bool {
+		return ok
+	}), func(next func(string, int, bool) error) func(string, int, bool) error {
+		return func(name string, value int, ok bool) error {
+			return next(name+"!", value, ok)
+		}
+	})
+
+	listener.Invoke("a", 1, true, func(name string, value int, ok bool) {
+		calls = append(calls, "finalware "+name)
+	})
+	listener.Invoke("b", 2, false)
+	listener.Pop(&event)
+	listener.Invoke("c", 3, true)
+
+	if expected := []string{"a!", "finalware a"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls are %v, expected %v", calls, expected)
+	}
+}
+
+func TestListenerSubscriptionUnsubscribe(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+
+	var calls []string
+	handler := func(int) {
+		calls = append(calls, "handler")
+	}
+	first := listener.Subscribe(handler)
+	second := listener.Subscribe(handler)
+	failing := listener.SubscribeError(func(int) error {
+		calls = append(calls, "error")
+		return nil
+	})
+
+	listener.Invoke(1)
+	if expected := []string{"handler", "handler", "error"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("calls are %v, expected %v", calls, expected)
+	}
+
+	first.Unsubscribe()
+	first.Unsubscribe()
+	select {
+	case <-first.Done():
+	default:
+		t.Error("subscription is not done after Unsubscribe")
+	}
+
+	// The subscriptions of the same function are removed one by one.
+	calls = nil
+	listener.Invoke(2)
+	if expected := []string{"handler", "error"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("calls are %v, expected %v", calls, expected)
+	}
+
+	second.Unsubscribe()
+	failing.Unsubscribe()
+	calls = nil
+	listener.Invoke(3)
+	if len(calls) != 0 {
+		t.Errorf("calls are %v after unsubscribing all", calls)
+	}
+}
+
+func TestListenerSubscribeContextRemovesOnCancel(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+	goroutines := runtime.NumGoroutine()
+
+	var calls atomic.Int32
+	handler := func(int) {
+		calls.Add(1)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if subscription := listener.SubscribeContext(canceled, handler); !isDone(subscription.Done()) {
+		t.Error("subscription of a canceled context is not done")
+	}
+
+	cancels := make([]context.CancelFunc, 50)
+	subscriptions := make([]ISubscription, 50)
+	for i := range subscriptions {
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(context.Background())
+		subscriptions[i] = listener.SubscribeContext(ctx, handler)
+	}
+
+	listener.Invoke(1)
+	if count := calls.Swap(0); count != 50 {
+		t.Fatalf("%d events are called, expected 50", count)
+	}
+
+	// Half of the subscriptions end with their context, the other half are unsubscribed first.
+	for i := range subscriptions {
+		if i%2 == 0 {
+			cancels[i]()
+			<-subscriptions[i].Done()
+		} else {
+			subscriptions[i].Unsubscribe()
+			cancels[i]()
+		}
+	}
+
+	listener.Invoke(2)
+	if count := calls.Load(); count != 0 {
+		t.Errorf("%d events are called after the subscriptions ended", count)
+	}
+
+	// The goroutines waiting for the contexts exit with their subscription.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if count := runtime.NumGoroutine(); count > goroutines {
+		t.Errorf("%d goroutines are running, %d before the subscriptions", count, goroutines)
+	}
+}
+
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestListenerInvokeAsyncMaxWorkers(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+
+	var active, peak, calls atomic.Int32
+	for i := 0; i < 12; i++ {
+		listener.Subscribe(func(int) {
+			current := active.Add(1)
+			for {
+				previous := peak.Load()
+				if current <= previous || peak.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			active.Add(-1)
+			calls.Add(1)
+		})
+	}
+
+	finished := int32(-1)
+	err := listener.InvokeAsync(1, InvokeOptions{MaxWorkers: 3}, func(int) {
+		finished = calls.Load()
+	}).Wait()
+
+	if err != nil || calls.Load() != 12 || finished != 12 {
+		t.Errorf("%d events are called, %d before the finalware, error %v", calls.Load(), finished, err)
+	}
+	if result := peak.Load(); result > 3 {
+		t.Errorf("%d events run at the same time, expected at most 3", result)
+	}
+}
+
+func TestListenerInvokeAsyncOrdered(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+
+	var mutex sync.Mutex
+	received := make([][]int, 3)
+	for i := range received {
+		index := i
+		listener.Subscribe(func(value int) {
+			// The later invocations are faster, so they would overtake the earlier ones if they were not ordered.
+			time.Sleep(time.Duration(20-value) * 100 * time.Microsecond)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			received[index] = append(received[index], value)
+		})
+	}
+
+	results := make([]IInvokeResult, 20)
+	for i := range results {
+		results[i] = listener.InvokeAsync(i, InvokeOptions{MaxWorkers: 4, Ordered: true})
+	}
+	for _, e := range results {
+		if err := e.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, values := range received {
+		for j, value := range values {
+			if value != j {
+				t.Fatalf("event %d received %v, expected the invocations in order", i, values)
+			}
+		}
+		if len(values) != 20 {
+			t.Errorf("event %d received %d invocations", i, len(values))
+		}
+	}
+}
+
+func TestListenerReplaysToLateEvents(t *testing.T) {
+	for _, mode := range []string{LISTENER_MODE_ONCE, LISTENER_MODE_RESETTABLE} {
+		listener := NewListenerV1WithMode[int](mode)
+		listener.Invoke(1)
+		listener.Invoke(2)
+
+		var received []int
+		event := func(value int) { received = append(received, value) }
+		listener.Push(&event)
+		listener.Subscribe(event)
+
+		if !reflect.DeepEqual(received, []int{1, 1}) {
+			t.Errorf("%s: late events received %v, expected the first invocation", mode, received)
+		}
+	}
+}
+
+func TestListenerSetReplayKeepsLastInvocations(t *testing.T) {
+	listener := NewListenerV1WithMode[int](LISTENER_MODE_EVERY_TIME)
+
+	var received []int
+	event := func(value int) { received = append(received, value) }
+
+	listener.Invoke(1)
+	listener.Subscribe(event)
+	if len(received) != 0 {
+		t.Fatalf("event received %v without replay", received)
+	}
+
+	listener.Reset()
+	listener.SetReplay(3)
+	for i := 1; i <= 5; i++ {
+		listener.Invoke(i)
+	}
+	received = nil
+	listener.Subscribe(event)
+	if !reflect.DeepEqual(received, []int{3, 4, 5}) {
+		t.Errorf("event received %v, expected the last 3 invocations", received)
+	}
+
+	listener.SetReplay(2)
+	received = nil
+	listener.Subscribe(event)
+	if !reflect.DeepEqual(received, []int{4, 5}) {
+		t.Errorf("event received %v, expected the last 2 invocations", received)
+	}
+}
+
+func TestListenerResetClearsReplays(t *testing.T) {
+	for _, mode := range []string{LISTENER_MODE_RESETTABLE, LISTENER_MODE_EVERY_TIME} {
+		listener := NewListenerV1WithMode[int](mode)
+		listener.SetReplay(2)
+		listener.Invoke(1)
+		listener.Reset()
+
+		if listener.IsInvoked() {
+			t.Errorf("%s: listener is invoked after Reset", mode)
+		}
+
+		var received []int
+		listener.Subscribe(func(value int) { received = append(received, value) })
+		if len(received) != 0 {
+			t.Errorf("%s: event received %v after Reset", mode, received)
+		}
+
+		listener.Invoke(2)
+		listener.Subscribe(func(value int) { received = append(received, value) })
+		if !reflect.DeepEqual(received, []int{2, 2}) {
+			t.Errorf("%s: events received %v, expected the invocation after Reset", mode, received)
+		}
+	}
+
+	listener := NewListenerV1[int]()
+	listener.Invoke(1)
+	listener.Reset()
+
+	var received []int
+	listener.Subscribe(func(value int) { received = append(received, value) })
+	if !listener.IsInvoked() || !reflect.DeepEqual(received, []int{1}) {
+		t.Errorf("once listener is reset, event received %v", received)
+	}
+}
+
+// The listeners still implement the interfaces of their push and invoke methods only.
+var (
+	_ IListenerV1[int]                          = &ListenerV1[int]{}
+	_ IListenerV2[int, int]                     = &ListenerV2[int, int]{}
+	_ IListenerV3[int, int, int]                = &ListenerV3[int, int, int]{}
+	_ IListenerV4[int, int, int, int]           = &ListenerV4[int, int, int, int]{}
+	_ IListenerV5[int, int, int, int, int]      = &ListenerV5[int, int, int, int, int]{}
+	_ IListenerV6[int, int, int, int, int, int] = &ListenerV6[int, int, int, int, int, int]{}
+	_ IListenerOf[int]                          = &ListenerOf[int]{}
+)
+
+func TestListenerV5(t *testing.T) {
+	listener := NewListenerV5WithMode[string, int, bool, float64, rune](LISTENER_MODE_EVERY_TIME)
+	failure := errors.New("failure")
+
+	var calls []string
+	event := func(a string, b int, c bool, d float64, e rune) {
+		calls = append(calls, "push "+a)
+	}
+	listener.Push(&event)
+	listener.SubscribeError(func(a string, b int, c bool, d float64, e rune) error {
+		if b != 1 || !c || d != 1.5 || e != 'x' {
+			t.Errorf("event received %s %d %v %v %c", a, b, c, d, e)
+		}
+		calls = append(calls, "error "+a)
+		return failure
+	})
+	listener.Use(FilterV5(func(a string, b int, c bool, d float64, e rune) bool {
+		return c
+	}))
+
+	err := listener.InvokeErr("a", 1, true, 1.5, 'x', func(a string, b int, c bool, d float64, e rune) {
+		calls = append(calls, "finalware "+a)
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("error is %v", err)
+	}
+
+	listener.Pop(&event)
+	listener.Invoke("b", 1, false, 1.5, 'x')
+	listener.Invoke("c", 1, true, 1.5, 'x')
+
+	if expected := []string{"push a", "error a", "finalware a", "error c"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("calls are %v, expected %v", calls, expected)
+	}
+}
+
+func TestListenerV6(t *testing.T) {
+	listener := NewListenerV6[string, int, bool, float64, rune, []int]()
+
+	var mutex sync.Mutex
+	var calls []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		listener.Subscribe(func(a string, b int, c bool, d float64, e rune, f []int) {
+			if a != "a" || b != 1 || !c || d != 1.5 || e != 'x' || len(f) != 2 {
+				t.Errorf("event received %s %d %v %v %c %v", a, b, c, d, e, f)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls = append(calls, name)
+		})
+	}
+
+	var finalized []string
+	err := listener.InvokeAsync("a", 1, true, 1.5, 'x', []int{1, 2}, InvokeOptions{}, func(string, int, bool, float64, rune, []int) {
+		finalized = append([]string{}, calls...)
+	}).Wait()
+	if err != nil || len(finalized) != 2 {
+		t.Errorf("finalware ran after %v, error %v", finalized, err)
+	}
+
+	listener.Invoke("b", 2, false, 0, 'y', nil)
+	if !listener.IsInvoked() || len(calls) != 2 {
+		t.Errorf("once listener called %v", calls)
+	}
+}
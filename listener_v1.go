@@ -1,7 +1,13 @@
 package utils
 
+import "context"
+
 type FinalwareV1[P1 any] func(P1)
 
+// MiddlewareV1 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV1[P1 any] func(next func(P1) error) func(P1) error
+
 type IListenerV1[P1 any] interface {
 	Push(*func(P1))
 	Pop(*func(P1))
@@ -9,83 +15,120 @@ type IListenerV1[P1 any] interface {
 	InvokeAll(P1, ...Finalware)
 }
 
-// ListenerV1 is a generic event listener for one parameter events.
+// IEventListenerV1 is the IListenerV1 of ListenerV1, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV1[P1 any] interface {
+	IListenerV1[P1]
+	PushPriority(*func(P1), int)
+	Subscribe(func(P1)) ISubscription
+	SubscribeContext(context.Context, func(P1)) ISubscription
+	SubscribePriority(func(P1), int) ISubscription
+	SubscribeError(func(P1) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1) error) ISubscription
+	Use(...MiddlewareV1[P1])
+	InvokeErr(P1, ...FinalwareV1[P1]) error
+	InvokeAllErr(P1, ...Finalware) error
+	InvokeAsync(P1, InvokeOptions, ...FinalwareV1[P1]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV1 is a generic event listener for one parameter events, built as ListenerOf is.
 // P1 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
 type ListenerV1[P1 any] struct {
-	events    []*func(P1)
-	isInvoked bool
+	listenerAdapter[P1, func(P1), func(P1) error, argsV1[P1]]
 }
 
-// NewListenerV1 returns a new instance of ListenerV1 with initial values of p1 and p2.
-func NewListenerV1[P1 any]() IListenerV1[P1] {
+// NewListenerV1 returns a new instance of ListenerV1, invoked once.
+func NewListenerV1[P1 any]() IEventListenerV1[P1] {
 	return &ListenerV1[P1]{}
 }
 
-// Push adds an event to the listener.
-func (l *ListenerV1[P1]) Push(event *func(a P1)) {
-	if event == nil {
-		return
-	}
-	// Check if the event already exists in the slice.
-	for _, e := range l.events {
-		if e == event {
-			return
-		}
-	}
-	l.events = append(l.events, event)
+// NewListenerV1WithMode returns a new instance of ListenerV1 with the given LISTENER_MODE_*.
+func NewListenerV1WithMode[P1 any](mode string) IEventListenerV1[P1] {
+	result := &ListenerV1[P1]{}
+	result.mode = mode
+	return result
 }
 
-// Pop removes an event from the listener.
-func (l *ListenerV1[P1]) Pop(event *func(a P1)) {
-	if event == nil {
-		return
-	}
-	// Find the index of the event in the slice.
-	for i, e := range l.events {
-		if e == event {
-			l.removeAt(i)
-			return
-		}
-	}
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV1[P1]) Use(middlewares ...MiddlewareV1[P1]) {
+	l.use(Select(middlewares, argsV1[P1]{}.middleware)...)
 }
 
-// Invoke calls all the events with the provided parameter.
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
 func (l *ListenerV1[P1]) Invoke(a P1, wares ...FinalwareV1[P1]) {
-	if l.isInvoked {
-		return
-	}
+	l.InvokeErr(a, wares...)
+}
 
-	l.isInvoked = true
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV1[P1]) InvokeAll(a P1, wares ...Finalware) {
+	l.InvokeAllErr(a, wares...)
+}
 
-	for i := range l.events {
-		event := *l.events[i]
-		event(a)
-	}
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV1[P1]) InvokeErr(a P1, wares ...FinalwareV1[P1]) error {
+	return l.invokeArgs(a, bindFinalwares(a, wares, argsV1[P1]{}.finalware))
+}
 
-	for _, e := range wares {
-		e(a)
-	}
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV1[P1]) InvokeAllErr(a P1, wares ...Finalware) error {
+	return l.invokeArgs(a, wares)
 }
 
-// Invoke calls all the events with the provided parameter.
-func (l *ListenerV1[P1]) InvokeAll(a P1, wares ...Finalware) {
-	if l.isInvoked {
-		return
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV1[P1]) InvokeAsync(a P1, options InvokeOptions, wares ...FinalwareV1[P1]) IInvokeResult {
+	return l.invokeArgsAsync(a, options, bindFinalwares(a, wares, argsV1[P1]{}.finalware))
+}
+
+// FilterV1 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV1[P1 any](predicate func(P1) bool) MiddlewareV1[P1] {
+	return func(next func(P1) error) func(P1) error {
+		return func(a P1) error {
+			if !predicate(a) {
+				return nil
+			}
+			return next(a)
+		}
 	}
+}
 
-	l.isInvoked = true
+// argsV1 is the listenerArgs of ListenerV1, converting its parameters to the argument of its handlers.
+type argsV1[P1 any] struct{}
 
-	for i := range l.events {
-		event := *l.events[i]
-		event(a)
+func (argsV1[P1]) event(event func(P1)) func(P1) error {
+	if event == nil {
+		return nil
 	}
+	return func(args P1) error {
+		event(args)
+		return nil
+	}
+}
 
-	for _, e := range wares {
-		e()
+func (argsV1[P1]) errorEvent(event func(P1) error) func(P1) error {
+	if event == nil {
+		return nil
+	}
+	return func(args P1) error {
+		return event(args)
+	}
+}
+
+func (c argsV1[P1]) middleware(middleware MiddlewareV1[P1]) func(func(P1) error) func(P1) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
+
+func (argsV1[P1]) finalware(ware FinalwareV1[P1], args P1) Finalware {
+	return func() {
+		ware(args)
 	}
 }
 
-// removeAt removes an event at the specified index from the listener's event queue.
-func (l *ListenerV1[P1]) removeAt(i int) {
-	l.events = RemoveAt(l.events, i)
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV1[P1]) params(handler func(P1) error) func(P1) error {
+	return func(a P1) error {
+		return handler(a)
+	}
 }
@@ -1,7 +1,13 @@
 package utils
 
+import "context"
+
 type FinalwareV2[P1 any, P2 any] func(P1, P2)
 
+// MiddlewareV2 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV2[P1 any, P2 any] func(next func(P1, P2) error) func(P1, P2) error
+
 type IListenerV2[P1 any, P2 any] interface {
 	Push(*func(P1, P2))
 	Pop(*func(P1, P2))
@@ -9,78 +15,122 @@ type IListenerV2[P1 any, P2 any] interface {
 	InvokeAll(P1, P2, ...Finalware)
 }
 
-// ListenerV2 is a generic type that represents an event listener that accepts two parameters
+// IEventListenerV2 is the IListenerV2 of ListenerV2, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV2[P1 any, P2 any] interface {
+	IListenerV2[P1, P2]
+	PushPriority(*func(P1, P2), int)
+	Subscribe(func(P1, P2)) ISubscription
+	SubscribeContext(context.Context, func(P1, P2)) ISubscription
+	SubscribePriority(func(P1, P2), int) ISubscription
+	SubscribeError(func(P1, P2) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1, P2) error) ISubscription
+	Use(...MiddlewareV2[P1, P2])
+	InvokeErr(P1, P2, ...FinalwareV2[P1, P2]) error
+	InvokeAllErr(P1, P2, ...Finalware) error
+	InvokeAsync(P1, P2, InvokeOptions, ...FinalwareV2[P1, P2]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV2 is a generic event listener for two parameter events, built as ListenerOf is with the tuple Args2.
 // P1 and P2 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
 type ListenerV2[P1 any, P2 any] struct {
-	events    []*func(P1, P2) // slice of function pointers that accept P1 and P2 as parameters
-	isInvoked bool
+	listenerAdapter[Args2[P1, P2], func(P1, P2), func(P1, P2) error, argsV2[P1, P2]]
 }
 
-// NewListenerV2 returns a new instance of ListenerV2 with initial values of p1 and p2.
-func NewListenerV2[P1 any, P2 any]() IListenerV2[P1, P2] {
+// NewListenerV2 returns a new instance of ListenerV2, invoked once.
+func NewListenerV2[P1 any, P2 any]() IEventListenerV2[P1, P2] {
 	return &ListenerV2[P1, P2]{}
 }
 
-// Push adds an event to the events slice
-func (l *ListenerV2[P1, P2]) Push(event *func(a P1, b P2)) {
-	if event == nil {
-		return
-	}
+// NewListenerV2WithMode returns a new instance of ListenerV2 with the given LISTENER_MODE_*.
+func NewListenerV2WithMode[P1 any, P2 any](mode string) IEventListenerV2[P1, P2] {
+	result := &ListenerV2[P1, P2]{}
+	result.mode = mode
+	return result
+}
 
-	l.events = append(l.events, event)
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV2[P1, P2]) Use(middlewares ...MiddlewareV2[P1, P2]) {
+	l.use(Select(middlewares, argsV2[P1, P2]{}.middleware)...)
 }
 
-// Pop removes an event from the events slice
-func (l *ListenerV2[P1, P2]) Pop(event *func(a P1, b P2)) {
-	if event == nil {
-		return
-	}
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerV2[P1, P2]) Invoke(a P1, b P2, wares ...FinalwareV2[P1, P2]) {
+	l.InvokeErr(a, b, wares...)
+}
 
-	for i, item := range l.events {
-		if item == event {
-			l.removeAt(i)
-			return
-		}
-	}
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV2[P1, P2]) InvokeAll(a P1, b P2, wares ...Finalware) {
+	l.InvokeAllErr(a, b, wares...)
 }
 
-// Invoke calls all the events in the events slice and passes a and b as parameters to them
-func (l *ListenerV2[P1, P2]) Invoke(a P1, b P2, wares ...FinalwareV2[P1, P2]) {
-	if l.isInvoked {
-		return
-	}
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV2[P1, P2]) InvokeErr(a P1, b P2, wares ...FinalwareV2[P1, P2]) error {
+	args := Args2[P1, P2]{a, b}
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsV2[P1, P2]{}.finalware))
+}
 
-	l.isInvoked = true
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV2[P1, P2]) InvokeAllErr(a P1, b P2, wares ...Finalware) error {
+	return l.invokeArgs(Args2[P1, P2]{a, b}, wares)
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b)
-	}
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV2[P1, P2]) InvokeAsync(a P1, b P2, options InvokeOptions, wares ...FinalwareV2[P1, P2]) IInvokeResult {
+	args := Args2[P1, P2]{a, b}
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsV2[P1, P2]{}.finalware))
+}
 
-	for _, e := range wares {
-		e(a, b)
+// FilterV2 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV2[P1 any, P2 any](predicate func(P1, P2) bool) MiddlewareV2[P1, P2] {
+	return func(next func(P1, P2) error) func(P1, P2) error {
+		return func(a P1, b P2) error {
+			if !predicate(a, b) {
+				return nil
+			}
+			return next(a, b)
+		}
 	}
 }
 
-// Invoke calls all the events with the provided parameter.
-func (l *ListenerV2[P1, P2]) InvokeAll(a P1, b P2, wares ...Finalware) {
-	if l.isInvoked {
-		return
-	}
+// argsV2 is the listenerArgs of ListenerV2, converting its parameters to the tuple Args2.
+type argsV2[P1 any, P2 any] struct{}
 
-	l.isInvoked = true
+func (argsV2[P1, P2]) event(event func(P1, P2)) func(Args2[P1, P2]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args2[P1, P2]) error {
+		event(args.A, args.B)
+		return nil
+	}
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b)
+func (argsV2[P1, P2]) errorEvent(event func(P1, P2) error) func(Args2[P1, P2]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args2[P1, P2]) error {
+		return event(args.A, args.B)
 	}
+}
+
+func (c argsV2[P1, P2]) middleware(middleware MiddlewareV2[P1, P2]) func(func(Args2[P1, P2]) error) func(Args2[P1, P2]) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
 
-	for _, e := range wares {
-		e()
+func (argsV2[P1, P2]) finalware(ware FinalwareV2[P1, P2], args Args2[P1, P2]) Finalware {
+	return func() {
+		ware(args.A, args.B)
 	}
 }
 
-// removeAt removes an element at a given index from the events slice
-func (l *ListenerV2[P1, P2]) removeAt(i int) {
-	l.events = RemoveAt(l.events, i)
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV2[P1, P2]) params(handler func(Args2[P1, P2]) error) func(P1, P2) error {
+	return func(a P1, b P2) error {
+		return handler(Args2[P1, P2]{a, b})
+	}
 }
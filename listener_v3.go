@@ -1,7 +1,13 @@
 package utils
 
+import "context"
+
 type FinalwareV3[P1 any, P2 any, P3 any] func(P1, P2, P3)
 
+// MiddlewareV3 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV3[P1 any, P2 any, P3 any] func(next func(P1, P2, P3) error) func(P1, P2, P3) error
+
 type IListenerV3[P1 any, P2 any, P3 any] interface {
 	Push(*func(P1, P2, P3))
 	Pop(*func(P1, P2, P3))
@@ -9,79 +15,122 @@ type IListenerV3[P1 any, P2 any, P3 any] interface {
 	InvokeAll(P1, P2, P3, ...Finalware)
 }
 
-// ListenerV3 is a generic type that represents an event listener that accepts two parameters
+// IEventListenerV3 is the IListenerV3 of ListenerV3, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV3[P1 any, P2 any, P3 any] interface {
+	IListenerV3[P1, P2, P3]
+	PushPriority(*func(P1, P2, P3), int)
+	Subscribe(func(P1, P2, P3)) ISubscription
+	SubscribeContext(context.Context, func(P1, P2, P3)) ISubscription
+	SubscribePriority(func(P1, P2, P3), int) ISubscription
+	SubscribeError(func(P1, P2, P3) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1, P2, P3) error) ISubscription
+	Use(...MiddlewareV3[P1, P2, P3])
+	InvokeErr(P1, P2, P3, ...FinalwareV3[P1, P2, P3]) error
+	InvokeAllErr(P1, P2, P3, ...Finalware) error
+	InvokeAsync(P1, P2, P3, InvokeOptions, ...FinalwareV3[P1, P2, P3]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV3 is a generic event listener for three parameter events, built as ListenerOf is with the tuple Args3.
 // P1 and P2 and P3 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
 type ListenerV3[P1 any, P2 any, P3 any] struct {
-	events    []*func(P1, P2, P3) // a slice of function pointers that can take P1, P2, P3 parameters
-	isInvoked bool
+	listenerAdapter[Args3[P1, P2, P3], func(P1, P2, P3), func(P1, P2, P3) error, argsV3[P1, P2, P3]]
 }
 
-// NewListenerV3 returns a new instance of ListenerV3 with initial values of p1 and p2.
-func NewListenerV3[P1 any, P2 any, P3 any]() IListenerV3[P1, P2, P3] {
+// NewListenerV3 returns a new instance of ListenerV3, invoked once.
+func NewListenerV3[P1 any, P2 any, P3 any]() IEventListenerV3[P1, P2, P3] {
 	return &ListenerV3[P1, P2, P3]{}
 }
 
-// Push adds an event to the listener's event queue.
-func (l *ListenerV3[P1, P2, P3]) Push(event *func(a P1, b P2, c P3)) {
-	if event == nil {
-		return
-	}
+// NewListenerV3WithMode returns a new instance of ListenerV3 with the given LISTENER_MODE_*.
+func NewListenerV3WithMode[P1 any, P2 any, P3 any](mode string) IEventListenerV3[P1, P2, P3] {
+	result := &ListenerV3[P1, P2, P3]{}
+	result.mode = mode
+	return result
+}
 
-	l.events = append(l.events, event)
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV3[P1, P2, P3]) Use(middlewares ...MiddlewareV3[P1, P2, P3]) {
+	l.use(Select(middlewares, argsV3[P1, P2, P3]{}.middleware)...)
 }
 
-// Pop removes an event from the listener's event queue.
-func (l *ListenerV3[P1, P2, P3]) Pop(event *func(a P1, b P2, c P3)) {
-	if event == nil {
-		return
-	}
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerV3[P1, P2, P3]) Invoke(a P1, b P2, c P3, wares ...FinalwareV3[P1, P2, P3]) {
+	l.InvokeErr(a, b, c, wares...)
+}
 
-	for i := 0; i < len(l.events); i++ {
-		item := l.events[i]
-		if item == event {
-			l.removeAt(i)
-			i--
-		}
-	}
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV3[P1, P2, P3]) InvokeAll(a P1, b P2, c P3, wares ...Finalware) {
+	l.InvokeAllErr(a, b, c, wares...)
 }
 
-// Invoke calls all the events in the listener's event queue with the given parameters.
-func (l *ListenerV3[P1, P2, P3]) Invoke(a P1, b P2, c P3, wares ...FinalwareV3[P1, P2, P3]) {
-	if l.isInvoked {
-		return
-	}
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV3[P1, P2, P3]) InvokeErr(a P1, b P2, c P3, wares ...FinalwareV3[P1, P2, P3]) error {
+	args := Args3[P1, P2, P3]{a, b, c}
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsV3[P1, P2, P3]{}.finalware))
+}
 
-	l.isInvoked = true
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV3[P1, P2, P3]) InvokeAllErr(a P1, b P2, c P3, wares ...Finalware) error {
+	return l.invokeArgs(Args3[P1, P2, P3]{a, b, c}, wares)
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b, c)
-	}
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV3[P1, P2, P3]) InvokeAsync(a P1, b P2, c P3, options InvokeOptions, wares ...FinalwareV3[P1, P2, P3]) IInvokeResult {
+	args := Args3[P1, P2, P3]{a, b, c}
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsV3[P1, P2, P3]{}.finalware))
+}
 
-	for _, e := range wares {
-		e(a, b, c)
+// FilterV3 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV3[P1 any, P2 any, P3 any](predicate func(P1, P2, P3) bool) MiddlewareV3[P1, P2, P3] {
+	return func(next func(P1, P2, P3) error) func(P1, P2, P3) error {
+		return func(a P1, b P2, c P3) error {
+			if !predicate(a, b, c) {
+				return nil
+			}
+			return next(a, b, c)
+		}
 	}
 }
 
-// Invoke calls all the events with the provided parameter.
-func (l *ListenerV3[P1, P2, P3]) InvokeAll(a P1, b P2, c P3, wares ...Finalware) {
-	if l.isInvoked {
-		return
-	}
+// argsV3 is the listenerArgs of ListenerV3, converting its parameters to the tuple Args3.
+type argsV3[P1 any, P2 any, P3 any] struct{}
 
-	l.isInvoked = true
+func (argsV3[P1, P2, P3]) event(event func(P1, P2, P3)) func(Args3[P1, P2, P3]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args3[P1, P2, P3]) error {
+		event(args.A, args.B, args.C)
+		return nil
+	}
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b, c)
+func (argsV3[P1, P2, P3]) errorEvent(event func(P1, P2, P3) error) func(Args3[P1, P2, P3]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args3[P1, P2, P3]) error {
+		return event(args.A, args.B, args.C)
 	}
+}
+
+func (c argsV3[P1, P2, P3]) middleware(middleware MiddlewareV3[P1, P2, P3]) func(func(Args3[P1, P2, P3]) error) func(Args3[P1, P2, P3]) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
 
-	for _, e := range wares {
-		e()
+func (argsV3[P1, P2, P3]) finalware(ware FinalwareV3[P1, P2, P3], args Args3[P1, P2, P3]) Finalware {
+	return func() {
+		ware(args.A, args.B, args.C)
 	}
 }
 
-// removeAt removes an event at the specified index from the listener's event queue.
-func (l *ListenerV3[P1, P2, P3]) removeAt(i int) {
-	l.events = RemoveAt(l.events, i)
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV3[P1, P2, P3]) params(handler func(Args3[P1, P2, P3]) error) func(P1, P2, P3) error {
+	return func(a P1, b P2, c P3) error {
+		return handler(Args3[P1, P2, P3]{a, b, c})
+	}
 }
@@ -1,7 +1,13 @@
 package utils
 
+import "context"
+
 type FinalwareV4[P1 any, P2 any, P3 any, P4 any] func(P1, P2, P3, P4)
 
+// MiddlewareV4 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV4[P1 any, P2 any, P3 any, P4 any] func(next func(P1, P2, P3, P4) error) func(P1, P2, P3, P4) error
+
 type IListenerV4[P1 any, P2 any, P3 any, P4 any] interface {
 	Push(*func(P1, P2, P3, P4))
 	Pop(*func(P1, P2, P3, P4))
@@ -9,79 +15,122 @@ type IListenerV4[P1 any, P2 any, P3 any, P4 any] interface {
 	InvokeAll(P1, P2, P3, P4, ...Finalware)
 }
 
-// ListenerV3 is a generic type that represents an event listener that accepts two parameters
-// P1 and P2 and P3 can be any type of parameters
+// IEventListenerV4 is the IListenerV4 of ListenerV4, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV4[P1 any, P2 any, P3 any, P4 any] interface {
+	IListenerV4[P1, P2, P3, P4]
+	PushPriority(*func(P1, P2, P3, P4), int)
+	Subscribe(func(P1, P2, P3, P4)) ISubscription
+	SubscribeContext(context.Context, func(P1, P2, P3, P4)) ISubscription
+	SubscribePriority(func(P1, P2, P3, P4), int) ISubscription
+	SubscribeError(func(P1, P2, P3, P4) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1, P2, P3, P4) error) ISubscription
+	Use(...MiddlewareV4[P1, P2, P3, P4])
+	InvokeErr(P1, P2, P3, P4, ...FinalwareV4[P1, P2, P3, P4]) error
+	InvokeAllErr(P1, P2, P3, P4, ...Finalware) error
+	InvokeAsync(P1, P2, P3, P4, InvokeOptions, ...FinalwareV4[P1, P2, P3, P4]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV4 is a generic event listener for four parameter events, built as ListenerOf is with the tuple Args4.
+// P1 and P2 and P3 and P4 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
 type ListenerV4[P1 any, P2 any, P3 any, P4 any] struct {
-	events    []*func(P1, P2, P3, P4)
-	isInvoked bool
+	listenerAdapter[Args4[P1, P2, P3, P4], func(P1, P2, P3, P4), func(P1, P2, P3, P4) error, argsV4[P1, P2, P3, P4]]
 }
 
-// NewListenerV4 returns a new instance of ListenerV3 with initial values of p1 and p2.
-func NewListenerV4[P1 any, P2 any, P3 any, P4 any]() IListenerV4[P1, P2, P3, P4] {
+// NewListenerV4 returns a new instance of ListenerV4, invoked once.
+func NewListenerV4[P1 any, P2 any, P3 any, P4 any]() IEventListenerV4[P1, P2, P3, P4] {
 	return &ListenerV4[P1, P2, P3, P4]{}
 }
 
-// Push adds an event to the event slice
-func (l *ListenerV4[P1, P2, P3, P4]) Push(event *func(a P1, b P2, c P3, d P4)) {
-	if event == nil {
-		return
-	}
+// NewListenerV4WithMode returns a new instance of ListenerV4 with the given LISTENER_MODE_*.
+func NewListenerV4WithMode[P1 any, P2 any, P3 any, P4 any](mode string) IEventListenerV4[P1, P2, P3, P4] {
+	result := &ListenerV4[P1, P2, P3, P4]{}
+	result.mode = mode
+	return result
+}
 
-	l.events = append(l.events, event)
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV4[P1, P2, P3, P4]) Use(middlewares ...MiddlewareV4[P1, P2, P3, P4]) {
+	l.use(Select(middlewares, argsV4[P1, P2, P3, P4]{}.middleware)...)
 }
 
-// Pop removes an event from the event slice
-func (l *ListenerV4[P1, P2, P3, P4]) Pop(event *func(a P1, b P2, c P3, d P4)) {
-	if event == nil {
-		return
-	}
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerV4[P1, P2, P3, P4]) Invoke(a P1, b P2, c P3, d P4, wares ...FinalwareV4[P1, P2, P3, P4]) {
+	l.InvokeErr(a, b, c, d, wares...)
+}
 
-	for i := 0; i < len(l.events); i++ {
-		item := l.events[i]
-		if item == event {
-			l.removeAt(i)
-			i--
-		}
-	}
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV4[P1, P2, P3, P4]) InvokeAll(a P1, b P2, c P3, d P4, wares ...Finalware) {
+	l.InvokeAllErr(a, b, c, d, wares...)
 }
 
-// Invoke calls all events in the event slice with the given parameters
-func (l *ListenerV4[P1, P2, P3, P4]) Invoke(a P1, b P2, c P3, d P4, wares ...FinalwareV4[P1, P2, P3, P4]) {
-	if l.isInvoked {
-		return
-	}
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV4[P1, P2, P3, P4]) InvokeErr(a P1, b P2, c P3, d P4, wares ...FinalwareV4[P1, P2, P3, P4]) error {
+	args := Args4[P1, P2, P3, P4]{a, b, c, d}
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsV4[P1, P2, P3, P4]{}.finalware))
+}
 
-	l.isInvoked = true
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV4[P1, P2, P3, P4]) InvokeAllErr(a P1, b P2, c P3, d P4, wares ...Finalware) error {
+	return l.invokeArgs(Args4[P1, P2, P3, P4]{a, b, c, d}, wares)
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b, c, d)
-	}
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV4[P1, P2, P3, P4]) InvokeAsync(a P1, b P2, c P3, d P4, options InvokeOptions, wares ...FinalwareV4[P1, P2, P3, P4]) IInvokeResult {
+	args := Args4[P1, P2, P3, P4]{a, b, c, d}
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsV4[P1, P2, P3, P4]{}.finalware))
+}
 
-	for _, e := range wares {
-		e(a, b, c, d)
+// FilterV4 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV4[P1 any, P2 any, P3 any, P4 any](predicate func(P1, P2, P3, P4) bool) MiddlewareV4[P1, P2, P3, P4] {
+	return func(next func(P1, P2, P3, P4) error) func(P1, P2, P3, P4) error {
+		return func(a P1, b P2, c P3, d P4) error {
+			if !predicate(a, b, c, d) {
+				return nil
+			}
+			return next(a, b, c, d)
+		}
 	}
 }
 
-// Invoke calls all the events with the provided parameter.
-func (l *ListenerV4[P1, P2, P3, P4]) InvokeAll(a P1, b P2, c P3, d P4, wares ...Finalware) {
-	if l.isInvoked {
-		return
-	}
+// argsV4 is the listenerArgs of ListenerV4, converting its parameters to the tuple Args4.
+type argsV4[P1 any, P2 any, P3 any, P4 any] struct{}
 
-	l.isInvoked = true
+func (argsV4[P1, P2, P3, P4]) event(event func(P1, P2, P3, P4)) func(Args4[P1, P2, P3, P4]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args4[P1, P2, P3, P4]) error {
+		event(args.A, args.B, args.C, args.D)
+		return nil
+	}
+}
 
-	for _, e := range l.events {
-		event := *e
-		event(a, b, c, d)
+func (argsV4[P1, P2, P3, P4]) errorEvent(event func(P1, P2, P3, P4) error) func(Args4[P1, P2, P3, P4]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args4[P1, P2, P3, P4]) error {
+		return event(args.A, args.B, args.C, args.D)
 	}
+}
+
+func (c argsV4[P1, P2, P3, P4]) middleware(middleware MiddlewareV4[P1, P2, P3, P4]) func(func(Args4[P1, P2, P3, P4]) error) func(Args4[P1, P2, P3, P4]) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
 
-	for _, e := range wares {
-		e()
+func (argsV4[P1, P2, P3, P4]) finalware(ware FinalwareV4[P1, P2, P3, P4], args Args4[P1, P2, P3, P4]) Finalware {
+	return func() {
+		ware(args.A, args.B, args.C, args.D)
 	}
 }
 
-// removeAt removes an event from the event slice at the given index
-func (l *ListenerV4[P1, P2, P3, P4]) removeAt(i int) {
-	l.events = RemoveAt(l.events, i)
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV4[P1, P2, P3, P4]) params(handler func(Args4[P1, P2, P3, P4]) error) func(P1, P2, P3, P4) error {
+	return func(a P1, b P2, c P3, d P4) error {
+		return handler(Args4[P1, P2, P3, P4]{a, b, c, d})
+	}
 }
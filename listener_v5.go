@@ -0,0 +1,136 @@
+package utils
+
+import "context"
+
+type FinalwareV5[P1 any, P2 any, P3 any, P4 any, P5 any] func(P1, P2, P3, P4, P5)
+
+// MiddlewareV5 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV5[P1 any, P2 any, P3 any, P4 any, P5 any] func(next func(P1, P2, P3, P4, P5) error) func(P1, P2, P3, P4, P5) error
+
+type IListenerV5[P1 any, P2 any, P3 any, P4 any, P5 any] interface {
+	Push(*func(P1, P2, P3, P4, P5))
+	Pop(*func(P1, P2, P3, P4, P5))
+	Invoke(P1, P2, P3, P4, P5, ...FinalwareV5[P1, P2, P3, P4, P5])
+	InvokeAll(P1, P2, P3, P4, P5, ...Finalware)
+}
+
+// IEventListenerV5 is the IListenerV5 of ListenerV5, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV5[P1 any, P2 any, P3 any, P4 any, P5 any] interface {
+	IListenerV5[P1, P2, P3, P4, P5]
+	PushPriority(*func(P1, P2, P3, P4, P5), int)
+	Subscribe(func(P1, P2, P3, P4, P5)) ISubscription
+	SubscribeContext(context.Context, func(P1, P2, P3, P4, P5)) ISubscription
+	SubscribePriority(func(P1, P2, P3, P4, P5), int) ISubscription
+	SubscribeError(func(P1, P2, P3, P4, P5) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1, P2, P3, P4, P5) error) ISubscription
+	Use(...MiddlewareV5[P1, P2, P3, P4, P5])
+	InvokeErr(P1, P2, P3, P4, P5, ...FinalwareV5[P1, P2, P3, P4, P5]) error
+	InvokeAllErr(P1, P2, P3, P4, P5, ...Finalware) error
+	InvokeAsync(P1, P2, P3, P4, P5, InvokeOptions, ...FinalwareV5[P1, P2, P3, P4, P5]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV5 is a generic event listener for five parameter events, built as ListenerOf is with the tuple Args5.
+// P1 and P2 and P3 and P4 and P5 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
+type ListenerV5[P1 any, P2 any, P3 any, P4 any, P5 any] struct {
+	listenerAdapter[Args5[P1, P2, P3, P4, P5], func(P1, P2, P3, P4, P5), func(P1, P2, P3, P4, P5) error, argsV5[P1, P2, P3, P4, P5]]
+}
+
+// NewListenerV5 returns a new instance of ListenerV5, invoked once.
+func NewListenerV5[P1 any, P2 any, P3 any, P4 any, P5 any]() IEventListenerV5[P1, P2, P3, P4, P5] {
+	return &ListenerV5[P1, P2, P3, P4, P5]{}
+}
+
+// NewListenerV5WithMode returns a new instance of ListenerV5 with the given LISTENER_MODE_*.
+func NewListenerV5WithMode[P1 any, P2 any, P3 any, P4 any, P5 any](mode string) IEventListenerV5[P1, P2, P3, P4, P5] {
+	result := &ListenerV5[P1, P2, P3, P4, P5]{}
+	result.mode = mode
+	return result
+}
+
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) Use(middlewares ...MiddlewareV5[P1, P2, P3, P4, P5]) {
+	l.use(Select(middlewares, argsV5[P1, P2, P3, P4, P5]{}.middleware)...)
+}
+
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) Invoke(a P1, b P2, c P3, d P4, e P5, wares ...FinalwareV5[P1, P2, P3, P4, P5]) {
+	l.InvokeErr(a, b, c, d, e, wares...)
+}
+
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) InvokeAll(a P1, b P2, c P3, d P4, e P5, wares ...Finalware) {
+	l.InvokeAllErr(a, b, c, d, e, wares...)
+}
+
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) InvokeErr(a P1, b P2, c P3, d P4, e P5, wares ...FinalwareV5[P1, P2, P3, P4, P5]) error {
+	args := Args5[P1, P2, P3, P4, P5]{a, b, c, d, e}
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsV5[P1, P2, P3, P4, P5]{}.finalware))
+}
+
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) InvokeAllErr(a P1, b P2, c P3, d P4, e P5, wares ...Finalware) error {
+	return l.invokeArgs(Args5[P1, P2, P3, P4, P5]{a, b, c, d, e}, wares)
+}
+
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV5[P1, P2, P3, P4, P5]) InvokeAsync(a P1, b P2, c P3, d P4, e P5, options InvokeOptions, wares ...FinalwareV5[P1, P2, P3, P4, P5]) IInvokeResult {
+	args := Args5[P1, P2, P3, P4, P5]{a, b, c, d, e}
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsV5[P1, P2, P3, P4, P5]{}.finalware))
+}
+
+// FilterV5 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV5[P1 any, P2 any, P3 any, P4 any, P5 any](predicate func(P1, P2, P3, P4, P5) bool) MiddlewareV5[P1, P2, P3, P4, P5] {
+	return func(next func(P1, P2, P3, P4, P5) error) func(P1, P2, P3, P4, P5) error {
+		return func(a P1, b P2, c P3, d P4, e P5) error {
+			if !predicate(a, b, c, d, e) {
+				return nil
+			}
+			return next(a, b, c, d, e)
+		}
+	}
+}
+
+// argsV5 is the listenerArgs of ListenerV5, converting its parameters to the tuple Args5.
+type argsV5[P1 any, P2 any, P3 any, P4 any, P5 any] struct{}
+
+func (argsV5[P1, P2, P3, P4, P5]) event(event func(P1, P2, P3, P4, P5)) func(Args5[P1, P2, P3, P4, P5]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args5[P1, P2, P3, P4, P5]) error {
+		event(args.A, args.B, args.C, args.D, args.E)
+		return nil
+	}
+}
+
+func (argsV5[P1, P2, P3, P4, P5]) errorEvent(event func(P1, P2, P3, P4, P5) error) func(Args5[P1, P2, P3, P4, P5]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args5[P1, P2, P3, P4, P5]) error {
+		return event(args.A, args.B, args.C, args.D, args.E)
+	}
+}
+
+func (c argsV5[P1, P2, P3, P4, P5]) middleware(middleware MiddlewareV5[P1, P2, P3, P4, P5]) func(func(Args5[P1, P2, P3, P4, P5]) error) func(Args5[P1, P2, P3, P4, P5]) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
+
+func (argsV5[P1, P2, P3, P4, P5]) finalware(ware FinalwareV5[P1, P2, P3, P4, P5], args Args5[P1, P2, P3, P4, P5]) Finalware {
+	return func() {
+		ware(args.A, args.B, args.C, args.D, args.E)
+	}
+}
+
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV5[P1, P2, P3, P4, P5]) params(handler func(Args5[P1, P2, P3, P4, P5]) error) func(P1, P2, P3, P4, P5) error {
+	return func(a P1, b P2, c P3, d P4, e P5) error {
+		return handler(Args5[P1, P2, P3, P4, P5]{a, b, c, d, e})
+	}
+}
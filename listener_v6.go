@@ -0,0 +1,136 @@
+package utils
+
+import "context"
+
+type FinalwareV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] func(P1, P2, P3, P4, P5, P6)
+
+// MiddlewareV6 wraps the handler of every event of a listener, e.g. to log, time or filter them,
+// running code before and after calling next, or not calling it.
+type MiddlewareV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] func(next func(P1, P2, P3, P4, P5, P6) error) func(P1, P2, P3, P4, P5, P6) error
+
+type IListenerV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] interface {
+	Push(*func(P1, P2, P3, P4, P5, P6))
+	Pop(*func(P1, P2, P3, P4, P5, P6))
+	Invoke(P1, P2, P3, P4, P5, P6, ...FinalwareV6[P1, P2, P3, P4, P5, P6])
+	InvokeAll(P1, P2, P3, P4, P5, P6, ...Finalware)
+}
+
+// IEventListenerV6 is the IListenerV6 of ListenerV6, with its subscriptions, middlewares, errors, asynchronous invocations and modes.
+type IEventListenerV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] interface {
+	IListenerV6[P1, P2, P3, P4, P5, P6]
+	PushPriority(*func(P1, P2, P3, P4, P5, P6), int)
+	Subscribe(func(P1, P2, P3, P4, P5, P6)) ISubscription
+	SubscribeContext(context.Context, func(P1, P2, P3, P4, P5, P6)) ISubscription
+	SubscribePriority(func(P1, P2, P3, P4, P5, P6), int) ISubscription
+	SubscribeError(func(P1, P2, P3, P4, P5, P6) error) ISubscription
+	SubscribeErrorContext(context.Context, func(P1, P2, P3, P4, P5, P6) error) ISubscription
+	Use(...MiddlewareV6[P1, P2, P3, P4, P5, P6])
+	InvokeErr(P1, P2, P3, P4, P5, P6, ...FinalwareV6[P1, P2, P3, P4, P5, P6]) error
+	InvokeAllErr(P1, P2, P3, P4, P5, P6, ...Finalware) error
+	InvokeAsync(P1, P2, P3, P4, P5, P6, InvokeOptions, ...FinalwareV6[P1, P2, P3, P4, P5, P6]) IInvokeResult
+	IsInvoked() bool
+	Reset()
+	SetReplay(int)
+}
+
+// ListenerV6 is a generic event listener for six parameter events, built as ListenerOf is with the tuple Args6.
+// P1 and P2 and P3 and P4 and P5 and P6 can be any type of parameters
+// It is safe for concurrent use, and invoked as set by its mode, see LISTENER_MODE_ONCE.
+type ListenerV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] struct {
+	listenerAdapter[Args6[P1, P2, P3, P4, P5, P6], func(P1, P2, P3, P4, P5, P6), func(P1, P2, P3, P4, P5, P6) error, argsV6[P1, P2, P3, P4, P5, P6]]
+}
+
+// NewListenerV6 returns a new instance of ListenerV6, invoked once.
+func NewListenerV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any]() IEventListenerV6[P1, P2, P3, P4, P5, P6] {
+	return &ListenerV6[P1, P2, P3, P4, P5, P6]{}
+}
+
+// NewListenerV6WithMode returns a new instance of ListenerV6 with the given LISTENER_MODE_*.
+func NewListenerV6WithMode[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any](mode string) IEventListenerV6[P1, P2, P3, P4, P5, P6] {
+	result := &ListenerV6[P1, P2, P3, P4, P5, P6]{}
+	result.mode = mode
+	return result
+}
+
+// Use adds middlewares wrapping the handler of every event, see ListenerOf.Use.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) Use(middlewares ...MiddlewareV6[P1, P2, P3, P4, P5, P6]) {
+	l.use(Select(middlewares, argsV6[P1, P2, P3, P4, P5, P6]{}.middleware)...)
+}
+
+// Invoke calls all the events with the given parameters, then the finalwares, as InvokeErr does, dropping their errors.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) Invoke(a P1, b P2, c P3, d P4, e P5, f P6, wares ...FinalwareV6[P1, P2, P3, P4, P5, P6]) {
+	l.InvokeErr(a, b, c, d, e, f, wares...)
+}
+
+// InvokeAll calls all the events with the given parameters, then the finalwares, as InvokeAllErr does, dropping their errors.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) InvokeAll(a P1, b P2, c P3, d P4, e P5, f P6, wares ...Finalware) {
+	l.InvokeAllErr(a, b, c, d, e, f, wares...)
+}
+
+// InvokeErr calls all the events with the given parameters, then the finalwares, and returns their errors joined, see ListenerOf.InvokeErr.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) InvokeErr(a P1, b P2, c P3, d P4, e P5, f P6, wares ...FinalwareV6[P1, P2, P3, P4, P5, P6]) error {
+	args := Args6[P1, P2, P3, P4, P5, P6]{a, b, c, d, e, f}
+	return l.invokeArgs(args, bindFinalwares(args, wares, argsV6[P1, P2, P3, P4, P5, P6]{}.finalware))
+}
+
+// InvokeAllErr calls all the events with the given parameters, then the finalwares, as InvokeErr does.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) InvokeAllErr(a P1, b P2, c P3, d P4, e P5, f P6, wares ...Finalware) error {
+	return l.invokeArgs(Args6[P1, P2, P3, P4, P5, P6]{a, b, c, d, e, f}, wares)
+}
+
+// InvokeAsync calls the events concurrently with the given parameters, see ListenerOf.InvokeAsync.
+func (l *ListenerV6[P1, P2, P3, P4, P5, P6]) InvokeAsync(a P1, b P2, c P3, d P4, e P5, f P6, options InvokeOptions, wares ...FinalwareV6[P1, P2, P3, P4, P5, P6]) IInvokeResult {
+	args := Args6[P1, P2, P3, P4, P5, P6]{a, b, c, d, e, f}
+	return l.invokeArgsAsync(args, options, bindFinalwares(args, wares, argsV6[P1, P2, P3, P4, P5, P6]{}.finalware))
+}
+
+// FilterV6 returns a middleware calling the handlers only for the parameters matching the predicate.
+func FilterV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any](predicate func(P1, P2, P3, P4, P5, P6) bool) MiddlewareV6[P1, P2, P3, P4, P5, P6] {
+	return func(next func(P1, P2, P3, P4, P5, P6) error) func(P1, P2, P3, P4, P5, P6) error {
+		return func(a P1, b P2, c P3, d P4, e P5, f P6) error {
+			if !predicate(a, b, c, d, e, f) {
+				return nil
+			}
+			return next(a, b, c, d, e, f)
+		}
+	}
+}
+
+// argsV6 is the listenerArgs of ListenerV6, converting its parameters to the tuple Args6.
+type argsV6[P1 any, P2 any, P3 any, P4 any, P5 any, P6 any] struct{}
+
+func (argsV6[P1, P2, P3, P4, P5, P6]) event(event func(P1, P2, P3, P4, P5, P6)) func(Args6[P1, P2, P3, P4, P5, P6]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args6[P1, P2, P3, P4, P5, P6]) error {
+		event(args.A, args.B, args.C, args.D, args.E, args.F)
+		return nil
+	}
+}
+
+func (argsV6[P1, P2, P3, P4, P5, P6]) errorEvent(event func(P1, P2, P3, P4, P5, P6) error) func(Args6[P1, P2, P3, P4, P5, P6]) error {
+	if event == nil {
+		return nil
+	}
+	return func(args Args6[P1, P2, P3, P4, P5, P6]) error {
+		return event(args.A, args.B, args.C, args.D, args.E, args.F)
+	}
+}
+
+func (c argsV6[P1, P2, P3, P4, P5, P6]) middleware(middleware MiddlewareV6[P1, P2, P3, P4, P5, P6]) func(func(Args6[P1, P2, P3, P4, P5, P6]) error) func(Args6[P1, P2, P3, P4, P5, P6]) error {
+	return adaptMiddleware(middleware, c.params, c.errorEvent)
+}
+
+func (argsV6[P1, P2, P3, P4, P5, P6]) finalware(ware FinalwareV6[P1, P2, P3, P4, P5, P6], args Args6[P1, P2, P3, P4, P5, P6]) Finalware {
+	return func() {
+		ware(args.A, args.B, args.C, args.D, args.E, args.F)
+	}
+}
+
+// params returns the handler of the parameters calling a handler of the tuple.
+func (argsV6[P1, P2, P3, P4, P5, P6]) params(handler func(Args6[P1, P2, P3, P4, P5, P6]) error) func(P1, P2, P3, P4, P5, P6) error {
+	return func(a P1, b P2, c P3, d P4, e P5, f P6) error {
+		return handler(Args6[P1, P2, P3, P4, P5, P6]{a, b, c, d, e, f})
+	}
+}
@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	OUTBOX_STATUS_PENDING string = "pending"
+	OUTBOX_STATUS_DONE    string = "done"
+	// OUTBOX_STATUS_FAILED is the status of an event whose delivery failed OutboxOptions.MaxAttempts times, no longer retried.
+	OUTBOX_STATUS_FAILED string = "failed"
+
+	OUTBOX_DEFAULT_BATCH_SIZE      int           = 100
+	OUTBOX_DEFAULT_POLL_INTERVAL   time.Duration = time.Second
+	OUTBOX_DEFAULT_MAX_ATTEMPTS    int           = 10
+	OUTBOX_DEFAULT_INITIAL_BACKOFF time.Duration = time.Second
+	OUTBOX_DEFAULT_MAX_BACKOFF     time.Duration = 5 * time.Minute
+	OUTBOX_DEFAULT_LEASE           time.Duration = time.Minute
+)
+
+// OutboxEvent is an event of the transactional outbox, recorded in the transaction of the writes raising it,
+// and delivered by an OutboxDispatcher once committed. Payload is the event serialized in JSON.
+type OutboxEvent struct {
+	Id            string
+	Topic         string
+	Payload       string
+	Status        string
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+}
+
+// IOutboxStore reads and updates the events of the outbox for the OutboxDispatchers, which may run in several processes.
+type IOutboxStore interface {
+	// FetchPending claims and returns at most limit pending events whose NextAttemptAt is not after now, oldest first.
+	// It sets their NextAttemptAt to leaseUntil in the same atomic operation, so no other dispatcher fetches them
+	// before the lease ends, when they are delivered again if the dispatcher did not mark them.
+	FetchPending(ctx context.Context, limit int, now time.Time, leaseUntil time.Time) ([]*OutboxEvent, error)
+	// MarkDone sets an event delivered.
+	MarkDone(ctx context.Context, id string) error
+	// MarkFailed saves the Status, Attempts, LastError and NextAttemptAt of an event whose delivery failed.
+	MarkFailed(ctx context.Context, event *OutboxEvent) error
+}
+
+// NewOutboxEvent returns a pending event of a topic, its payload being the event serialized in JSON.
+func NewOutboxEvent(topic string, event interface{}) (*OutboxEvent, error) {
+	var payload string
+	if err := ObjectToJson(event, &payload); err != nil {
+		return nil, err
+	}
+
+	id, err := newOutboxId()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &OutboxEvent{Id: id, Topic: topic, Payload: payload, Status: OUTBOX_STATUS_PENDING, CreatedAt: now, NextAttemptAt: now}, nil
+}
+
+// RecordOutboxEvent records an event with a catalogued action, executed on db, which is the transaction of the writes raising the event,
+// so the event is delivered only if they are committed. The fields of OutboxEvent are bound to its placeholders,
+// its times requiring SetType as for any model:
+//
+//	<action name="RecordEvent">
+//		<param name="id" type="guid"/><param name="topic"/><param name="payload"/><param name="createdAt" type="datetime"/>
+//		<text>INSERT INTO outbox (id, topic, payload, status, attempts, created_at, next_attempt_at)
+//		VALUES ({{id}}, {{topic}}, {{payload}}, 'pending', 0, {{createdAt}}, {{createdAt}})</text>
+//	</action>
+func RecordOutboxEvent[R, T any](db IGormDB[R, T], controller string, action string, claims IClaims, topic string, event interface{}) error {
+	record, err := NewOutboxEvent(topic, event)
+	if err != nil {
+		return err
+	}
+
+	return ExecuteMultipleResult(db, controller, action, claims, record)
+}
+
+// RecordOutboxTopic records a typed event of a topic, as RecordOutboxEvent does.
+func RecordOutboxTopic[R, T, E any](db IGormDB[R, T], controller string, action string, claims IClaims, topic Topic[E], event E) error {
+	return RecordOutboxEvent(db, controller, action, claims, topic.name, event)
+}
+
+// OutboxCatalogStore is the IOutboxStore of a table read and updated by catalogued actions of a controller:
+// FetchAction binds {{limit}}, {{now}} and {{leaseUntil}}, and returns the rows of OutboxEvent it claims,
+// DoneAction binds {{id}}, and FailAction the fields of OutboxEvent.
+// FetchAction must claim its rows atomically, e.g. on SQL Server:
+//
+//	<action name="FetchEvents">
+//		<param name="limit" type="int"/><param name="now" type="datetime"/><param name="leaseUntil" type="datetime"/>
+//		<text>WITH batch AS (SELECT TOP ({{limit}}) * FROM outbox WITH (UPDLOCK, READPAST, ROWLOCK)
+//			WHERE status = 'pending' AND next_attempt_at <= {{now}} ORDER BY created_at)
+//		UPDATE batch SET next_attempt_at = {{leaseUntil}} OUTPUT inserted.*</text>
+//	</action>
+//	<action name="DoneEvent">
+//		<param name="id" type="string"/>
+//		<text>UPDATE outbox SET status = 'done' WHERE id = {{id}}</text>
+//	</action>
+//	<action name="FailEvent">
+//		<param name="id" type="string"/><param name="status" type="string"/><param name="attempts" type="int"/>
+//		<param name="lastError" type="string"/><param name="nextAttemptAt" type="datetime"/>
+//		<text>UPDATE outbox SET status = {{status}}, attempts = {{attempts}}, last_error = {{lastError}},
+//			next_attempt_at = {{nextAttemptAt}} WHERE id = {{id}}</text>
+//	</action>
+//
+// and on PostgreSQL with an UPDATE ... WHERE id IN (SELECT ... FOR UPDATE SKIP LOCKED) RETURNING *.
+// The queries run with the context of the dispatcher when DB has a WithContext method returning T, e.g. *gorm.DB.
+type OutboxCatalogStore[R, T any] struct {
+	DB          IGormDB[R, T]
+	Controller  string
+	FetchAction string
+	DoneAction  string
+	FailAction  string
+}
+
+type outboxFetchRequest struct {
+	Limit      int
+	Now        time.Time
+	LeaseUntil time.Time
+}
+
+type outboxDoneRequest struct {
+	Id string
+}
+
+func (s *OutboxCatalogStore[R, T]) FetchPending(ctx context.Context, limit int, now time.Time, leaseUntil time.Time) ([]*OutboxEvent, error) {
+	db, err := s.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*OutboxEvent{}
+	if err := Execute(db, s.Controller, s.FetchAction, nil, &outboxFetchRequest{Limit: limit, Now: now, LeaseUntil: leaseUntil}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *OutboxCatalogStore[R, T]) MarkDone(ctx context.Context, id string) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	return ExecuteMultipleResult(db, s.Controller, s.DoneAction, nil, &outboxDoneRequest{Id: id})
+}
+
+func (s *OutboxCatalogStore[R, T]) MarkFailed(ctx context.Context, event *OutboxEvent) error {
+	db, err := s.db(ctx)
+	if err != nil {
+		return err
+	}
+	return ExecuteMultipleResult(db, s.Controller, s.FailAction, nil, event)
+}
+
+// db returns the DB of the store bound to the context, or the error of a context already done.
+func (s *OutboxCatalogStore[R, T]) db(ctx context.Context) (IGormDB[R, T], error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if db, ok := any(s.DB).(interface{ WithContext(context.Context) T }); ok {
+		if result, ok := any(db.WithContext(ctx)).(IGormDB[R, T]); ok {
+			return result, nil
+		}
+	}
+	return s.DB, nil
+}
+
+// MemoryOutboxStore is an IOutboxStore kept in memory, e.g. to test the dispatch of the events of a service.
+type MemoryOutboxStore struct {
+	mutex  sync.Mutex
+	events []*OutboxEvent
+}
+
+// NewMemoryOutboxStore returns a new instance of MemoryOutboxStore.
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{}
+}
+
+// Record adds a pending event of a topic, as RecordOutboxEvent does in a table.
+func (s *MemoryOutboxStore) Record(topic string, event interface{}) error {
+	record, err := NewOutboxEvent(topic, event)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.events = append(s.events, record)
+	return nil
+}
+
+// Events returns a copy of the events, whatever their status.
+func (s *MemoryOutboxStore) Events() []OutboxEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return Select(s.events, func(e *OutboxEvent) OutboxEvent {
+		return *e
+	})
+}
+
+func (s *MemoryOutboxStore) FetchPending(ctx context.Context, limit int, now time.Time, leaseUntil time.Time) ([]*OutboxEvent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result []*OutboxEvent
+	for _, e := range s.events {
+		if len(result) >= limit {
+			break
+		}
+		if e.Status == OUTBOX_STATUS_PENDING && !e.NextAttemptAt.After(now) {
+			e.NextAttemptAt = leaseUntil
+			event := *e
+			result = append(result, &event)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryOutboxStore) MarkDone(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, e := range s.events {
+		if e.Id == id {
+			e.Status = OUTBOX_STATUS_DONE
+		}
+	}
+	return nil
+}
+
+func (s *MemoryOutboxStore) MarkFailed(ctx context.Context, event *OutboxEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, e := range s.events {
+		if e.Id == event.Id {
+			e.Status, e.Attempts, e.LastError, e.NextAttemptAt = event.Status, event.Attempts, event.LastError, event.NextAttemptAt
+		}
+	}
+	return nil
+}
+
+// OutboxHandler delivers an event of the outbox, which is retried if it returns an error.
+type OutboxHandler func(ctx context.Context, event *OutboxEvent) error
+
+// OutboxBusHandler returns the handler publishing the events on a bus, synchronously, so they are retried if a subscriber fails.
+// The payload is decoded into the type of the topic declared by NewTopic, or published as a json.RawMessage.
+func OutboxBusHandler(bus *EventBus) OutboxHandler {
+	return func(ctx context.Context, event *OutboxEvent) error {
+		eventTopicTypesMutex.Lock()
+		eventType, ok := eventTopicTypes[event.Topic]
+		eventTopicTypesMutex.Unlock()
+
+		if !ok {
+			return bus.Publish(ctx, event.Topic, json.RawMessage(event.Payload))
+		}
+
+		value := reflect.New(eventType)
+		if err := JsonToObject(event.Payload, value.Interface()); err != nil {
+			return err
+		}
+		return bus.Publish(ctx, event.Topic, value.Elem().Interface())
+	}
+}
+
+// OutboxOptions sets how an OutboxDispatcher polls and retries, the zero values being replaced by the OUTBOX_DEFAULT_*.
+type OutboxOptions struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// MaxAttempts is the number of deliveries of an event before it is set OUTBOX_STATUS_FAILED.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled for each next one up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Lease is how long the events fetched are claimed by the dispatcher, which must deliver a batch in less time.
+	Lease time.Duration
+	// OnError receives the errors of the store met by Run, e.g. to log them, which are only printed in development when nil.
+	OnError func(error)
+}
+
+// OutboxDispatcher delivers the pending events of an outbox store with a handler, retrying the failed ones with backoff.
+// Several dispatchers can share a store, each event being claimed by one of them for OutboxOptions.Lease.
+// The delivery is at least once: an event whose lease ends before it is marked is delivered again.
+type OutboxDispatcher struct {
+	store   IOutboxStore
+	handler OutboxHandler
+	options OutboxOptions
+}
+
+// NewOutboxDispatcher returns a new instance of OutboxDispatcher, e.g. delivering to the listeners of a bus with OutboxBusHandler.
+func NewOutboxDispatcher(store IOutboxStore, handler OutboxHandler, options OutboxOptions) *OutboxDispatcher {
+	options.BatchSize = IIF(options.BatchSize > 0, options.BatchSize, OUTBOX_DEFAULT_BATCH_SIZE)
+	options.PollInterval = IIF(options.PollInterval > 0, options.PollInterval, OUTBOX_DEFAULT_POLL_INTERVAL)
+	options.MaxAttempts = IIF(options.MaxAttempts > 0, options.MaxAttempts, OUTBOX_DEFAULT_MAX_ATTEMPTS)
+	options.InitialBackoff = IIF(options.InitialBackoff > 0, options.InitialBackoff, OUTBOX_DEFAULT_INITIAL_BACKOFF)
+	options.MaxBackoff = IIF(options.MaxBackoff > 0, options.MaxBackoff, OUTBOX_DEFAULT_MAX_BACKOFF)
+	options.Lease = IIF(options.Lease > 0, options.Lease, OUTBOX_DEFAULT_LEASE)
+
+	return &OutboxDispatcher{store: store, handler: handler, options: options}
+}
+
+// Run dispatches the pending events every PollInterval until the context is done, and returns the error of the context.
+// A batch is fetched again at once while it is full. The errors of the store are given to OutboxOptions.OnError,
+// and retried on the next poll.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		count, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			if d.options.OnError != nil {
+				d.options.OnError(err)
+			} else {
+				consoleError("OutboxDispatcher", "outbox", "dispatch", "", err)
+			}
+		}
+		timer.Reset(IIF(err == nil && count >= d.options.BatchSize, 0, d.options.PollInterval))
+	}
+}
+
+// Dispatch delivers one batch of pending events, and returns the number of events fetched.
+// A failed delivery is not an error: the event is saved with its next attempt, or set OUTBOX_STATUS_FAILED.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	events, err := d.store.FetchPending(ctx, d.options.BatchSize, now, now.Add(d.options.Lease))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return len(events), ctx.Err()
+		}
+
+		event := e
+		if err = recoverError(func() error { return d.handler(ctx, event) }); err == nil {
+			err = d.store.MarkDone(ctx, event.Id)
+		} else {
+			err = d.store.MarkFailed(ctx, d.fail(event, err))
+		}
+		if err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// fail sets the attempt of an event failed, and when to retry it.
+func (d *OutboxDispatcher) fail(event *OutboxEvent, err error) *OutboxEvent {
+	event.Attempts++
+	event.LastError = err.Error()
+	if event.Attempts >= d.options.MaxAttempts {
+		event.Status = OUTBOX_STATUS_FAILED
+		return event
+	}
+
+	backoff := d.options.InitialBackoff
+	for i := 1; i < event.Attempts && backoff < d.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.options.MaxBackoff {
+		backoff = d.options.MaxBackoff
+	}
+
+	event.Status = OUTBOX_STATUS_PENDING
+	event.NextAttemptAt = time.Now().UTC().Add(backoff)
+	return event
+}
+
+// newOutboxId returns a random UUID, the id of a new event.
+func newOutboxId() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	bytes[6] = bytes[6]&0x0f | 0x40
+	bytes[8] = bytes[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:]), nil
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutboxDispatcherRetriesWithBackoff(t *testing.T) {
+	store := NewMemoryOutboxStore()
+	if err := store.Record("order.created", map[string]int{"id": 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("failure")
+	deliveries := 0
+	dispatcher := NewOutboxDispatcher(store, func(ctx context.Context, event *OutboxEvent) error {
+		deliveries++
+		if deliveries < 4 {
+			return failure
+		}
+		return nil
+	}, OutboxOptions{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: 3 * time.Hour})
+
+	for _, backoff := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		start := time.Now().UTC()
+		if count, err := dispatcher.Dispatch(context.Background()); count != 1 || err != nil {
+			t.Fatalf("dispatch returned %d, %v", count, err)
+		}
+
+		event := store.Events()[0]
+		if event.Status != OUTBOX_STATUS_PENDING || event.LastError != failure.Error() ||
+			event.NextAttemptAt.Before(start.Add(backoff)) || event.NextAttemptAt.After(time.Now().UTC().Add(backoff)) {
+			t.Fatalf("event after %d attempts is %+v, expected a backoff of %s", event.Attempts, event, backoff)
+		}
+
+		// Nothing is due before the backoff ends.
+		if count, _ := dispatcher.Dispatch(context.Background()); count != 0 {
+			t.Fatalf("dispatch fetched %d events during the backoff", count)
+		}
+		store.events[0].NextAttemptAt = time.Now().UTC()
+	}
+
+	if count, err := dispatcher.Dispatch(context.Background()); count != 1 || err != nil {
+		t.Fatalf("dispatch returned %d, %v", count, err)
+	}
+	if event := store.Events()[0]; event.Status != OUTBOX_STATUS_DONE || event.Attempts != 3 || deliveries != 4 {
+		t.Errorf("event is %+v after %d deliveries", event, deliveries)
+	}
+}
+
+func TestOutboxDispatcherFailsAfterMaxAttempts(t *testing.T) {
+	store := NewMemoryOutboxStore()
+	store.Record("order.created", 1)
+
+	dispatcher := NewOutboxDispatcher(store, func(ctx context.Context, event *OutboxEvent) error {
+		panic("boom")
+	}, OutboxOptions{MaxAttempts: 2, InitialBackoff: time.Nanosecond})
+
+	for i := 0; i < 3; i++ {
+		dispatcher.Dispatch(context.Background())
+		time.Sleep(time.Millisecond)
+	}
+
+	if event := store.Events()[0]; event.Status != OUTBOX_STATUS_FAILED || event.Attempts != 2 {
+		t.Errorf("event is %+v", event)
+	}
+}
+
+func TestOutboxDispatchersDeliverEachEventOnce(t *testing.T) {
+	store := NewMemoryOutboxStore()
+	for i := 0; i < 200; i++ {
+		store.Record("order.created", i)
+	}
+
+	var mutex sync.Mutex
+	deliveries := map[string]int{}
+	handler := func(ctx context.Context, event *OutboxEvent) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		deliveries[event.Id]++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		dispatcher := NewOutboxDispatcher(store, handler, OutboxOptions{BatchSize: 10})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if count, err := dispatcher.Dispatch(context.Background()); count == 0 || err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(deliveries) != 200 {
+		t.Errorf("%d events are delivered", len(deliveries))
+	}
+	for id, count := range deliveries {
+		if count != 1 {
+			t.Errorf("event %s is delivered %d times", id, count)
+		}
+	}
+}
+
+type failingOutboxStore struct {
+	MemoryOutboxStore
+	err error
+}
+
+func (s *failingOutboxStore) FetchPending(ctx context.Context, limit int, now time.Time, leaseUntil time.Time) ([]*OutboxEvent, error) {
+	return nil, s.err
+}
+
+func TestOutboxDispatcherRunReportsStoreErrors(t *testing.T) {
+	store := &failingOutboxStore{err: errors.New("connection refused")}
+	errs := make(chan error, 1)
+
+	dispatcher := NewOutboxDispatcher(store, func(ctx context.Context, event *OutboxEvent) error {
+		return nil
+	}, OutboxOptions{PollInterval: time.Hour, OnError: func(err error) {
+		errs <- err
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- dispatcher.Run(ctx)
+	}()
+
+	if err := <-errs; err != store.err {
+		t.Errorf("error is %v", err)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("run returned %v", err)
+	}
+}
+
+// The actions documented by OutboxCatalogStore, rendered with the requests of the store.
+func TestOutboxCatalogStoreActionsRender(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		text     string
+		params   []XmlParam
+		request  interface{}
+		expected []string
+	}{
+		{`WITH batch AS (SELECT TOP ({{limit}}) * FROM outbox WITH (UPDLOCK, READPAST, ROWLOCK)
+			WHERE status = 'pending' AND next_attempt_at <= {{now}} ORDER BY created_at)
+		UPDATE batch SET next_attempt_at = {{leaseUntil}} OUTPUT inserted.*`,
+			[]XmlParam{{Name: "limit", Type: "int"}, {Name: "now", Type: "datetime"}, {Name: "leaseUntil", Type: "datetime"}},
+			&outboxFetchRequest{Limit: 10, Now: now, LeaseUntil: now.Add(time.Minute)},
+			[]string{"TOP (10)", "next_attempt_at <= '2024-05-01 08:30:00", "next_attempt_at = '2024-05-01 08:31:00"}},
+		{`UPDATE outbox SET status = 'done' WHERE id = {{id}}`,
+			[]XmlParam{{Name: "id", Type: "string"}},
+			&outboxDoneRequest{Id: "evt-1"},
+			[]string{"id = N'evt-1'"}},
+		{`UPDATE outbox SET status = {{status}}, attempts = {{attempts}}, last_error = {{lastError}},
+			next_attempt_at = {{nextAttemptAt}} WHERE id = {{id}}`,
+			[]XmlParam{{Name: "id", Type: "string"}, {Name: "status", Type: "string"}, {Name: "attempts", Type: "int"},
+				{Name: "lastError", Type: "string"}, {Name: "nextAttemptAt", Type: "datetime"}},
+			&OutboxEvent{Id: "evt-1", Status: OUTBOX_STATUS_PENDING, Attempts: 3, LastError: "it's down", NextAttemptAt: now},
+			[]string{"status = N'pending'", "attempts = 3", "last_error = N'it''s down'", "next_attempt_at = '2024-05-01 08:30:00", "id = N'evt-1'"}},
+	}
+
+	for _, test := range tests {
+		template, err := ParseQueryTemplate(test.text, test.params...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, err := template.Render(TemplateValues{}.Bind(test.request))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range test.expected {
+			if !strings.Contains(query, e) {
+				t.Errorf("query %q does not contain %q", query, e)
+			}
+		}
+	}
+}
+
+type outboxTestDB struct {
+	ctx context.Context
+}
+
+func (d *outboxTestDB) Rows() (*sql.Rows, error) {
+	return nil, nil
+}
+
+func (d *outboxTestDB) Raw(sql string, values ...interface{}) *outboxTestDB {
+	return d
+}
+
+func (d *outboxTestDB) ScanRows(rows *sql.Rows, dest interface{}) error {
+	return nil
+}
+
+func (d *outboxTestDB) WithContext(ctx context.Context) *outboxTestDB {
+	return &outboxTestDB{ctx: ctx}
+}
+
+func TestOutboxCatalogStoreUsesContext(t *testing.T) {
+	store := &OutboxCatalogStore[*sql.Rows, *outboxTestDB]{DB: &outboxTestDB{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if db, err := store.db(ctx); err != nil || db.(*outboxTestDB).ctx != ctx {
+		t.Errorf("db is %+v, %v", db, err)
+	}
+
+	cancel()
+	if _, err := store.FetchPending(ctx, 10, time.Now(), time.Now()); !errors.Is(err, context.Canceled) {
+		t.Errorf("fetch returned %v", err)
+	}
+	if err := store.MarkDone(ctx, "evt-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("mark done returned %v", err)
+	}
+}
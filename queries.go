@@ -2,33 +2,175 @@ package utils
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 var REMOVE_PATHS = []string{"cmd/main", "cmd\\main"}
 
+var ERROR_ACTION_NOT_FOUND = errors.New("action_not_found")
+
 type XmlNameNode struct {
-	Name string `xml:"name,attr"`
+	Name string `xml:"name,attr" yaml:"name"`
+}
+
+// XmlParam declares a named placeholder of an action, e.g. <param name="from" type="datetime"/>.
+type XmlParam struct {
+	Name     string `xml:"name,attr" yaml:"name" json:"name"`
+	Type     string `xml:"type,attr" yaml:"type" json:"type"`
+	Required bool   `xml:"required,attr" yaml:"required" json:"required"`
 }
 
+// XmlText is a query text of an action, e.g. <text dialect="postgres">...</text>.
+// A text without dialect is the default one.
+type XmlText struct {
+	Dialect string `xml:"dialect,attr"`
+	Value   string `xml:",chardata"`
+}
+
+const (
+	ACTION_KIND_EXECUTE            string = "execute"
+	ACTION_KIND_EXECUTE_ID         string = "id"
+	ACTION_KIND_MULTIPLE_RESULT    string = "multiple"
+	ACTION_KIND_ID_MULTIPLE_RESULT string = "id-multiple"
+	ACTION_KIND_FILTER_PAGINATION  string = "pagination"
+	ACTION_TYPE_SEPARATOR          string = ","
+)
+
+// XmlAction is an action of a controller.
+// Request, Response and Kind are optional, and only used to generate typed wrappers:
+// Kind names the Execute function to call, Request the type of its request (or id, or filters and paging, comma separated),
+// and Response the types of its results, comma separated.
 type XmlAction struct {
-	XmlNameNode
-	XMLName xml.Name `xml:"action"`
-	Text    string   `xml:"text"`
+	XmlNameNode `yaml:",inline"`
+	XMLName     xml.Name   `xml:"action" yaml:"-"`
+	Kind        string     `xml:"kind,attr" yaml:"kind"`
+	Request     string     `xml:"request,attr" yaml:"request"`
+	Response    string     `xml:"response,attr" yaml:"response"`
+	Params      []XmlParam `xml:"param" yaml:"params"`
+	Texts       []XmlText  `xml:"text" yaml:"-"`
+
+	// Text is the default text of the action, kept up to date by the catalog loaders and SetText,
+	// and read by FindText when Texts has no default text.
+	//
+	// Deprecated: use FindText and SetText, which also handle the dialect variants.
+	Text string `xml:"-" yaml:"-"`
+}
+
+// FindText returns the query text written for the given dialect,
+// falling back to the default text when the action has no variant for it.
+// It returns an error wrapping ERROR_ACTION_NOT_FOUND when the action has neither.
+func (a *XmlAction) FindText(dialect string) (string, error) {
+	var result *XmlText
+	for i, e := range a.Texts {
+		if e.Dialect == dialect {
+			return e.Value, nil
+		}
+		if e.Dialect == DIALECT_DEFAULT && result == nil {
+			result = &a.Texts[i]
+		}
+	}
+
+	if result == nil {
+		if a.Text != "" {
+			return a.Text, nil
+		}
+		return "", fmt.Errorf("%w: %s has no text for the dialect %q", ERROR_ACTION_NOT_FOUND, a.Name, dialect)
+	}
+	return result.Value, nil
+}
+
+// SetText sets the query text of the given dialect, replacing the existing one.
+func (a *XmlAction) SetText(dialect string, value string) {
+	if dialect == DIALECT_DEFAULT {
+		a.Text = value
+	}
+
+	for i := range a.Texts {
+		if a.Texts[i].Dialect == dialect {
+			a.Texts[i].Value = value
+			return
+		}
+	}
+	a.Texts = append(a.Texts, XmlText{Dialect: dialect, Value: value})
+}
+
+// UnmarshalXML reads an action written in XML, setting Text to its default text.
+func (a *XmlAction) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type xmlAction XmlAction
+	if err := d.DecodeElement((*xmlAction)(a), &start); err != nil {
+		return err
+	}
+
+	for _, e := range a.Texts {
+		if e.Dialect == DIALECT_DEFAULT {
+			a.Text = e.Value
+			break
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML reads an action written in YAML, where the default text is "text"
+// and the dialect variants are listed under "dialects":
+//
+//	name: GetList
+//	text: select top 10 * from Orders
+//	dialects:
+//	  postgres: select * from orders limit 10
+func (a *XmlAction) UnmarshalYAML(value *yaml.Node) error {
+	node := struct {
+		Name     string            `yaml:"name"`
+		Kind     string            `yaml:"kind"`
+		Request  string            `yaml:"request"`
+		Response string            `yaml:"response"`
+		Params   []XmlParam        `yaml:"params"`
+		Text     string            `yaml:"text"`
+		Dialects map[string]string `yaml:"dialects"`
+	}{}
+
+	if err := value.Decode(&node); err != nil {
+		return err
+	}
+
+	a.Name = node.Name
+	a.Kind = node.Kind
+	a.Request = node.Request
+	a.Response = node.Response
+	a.Params = node.Params
+	if node.Text != "" {
+		a.SetText(DIALECT_DEFAULT, node.Text)
+	}
+
+	dialects := make([]string, 0, len(node.Dialects))
+	for k := range node.Dialects {
+		dialects = append(dialects, k)
+	}
+	sort.Strings(dialects)
+
+	for _, e := range dialects {
+		a.SetText(e, node.Dialects[e])
+	}
+
+	return nil
 }
 
 type XmlController struct {
-	XmlNameNode
-	XMLName xml.Name    `xml:"controller"`
-	Actions []XmlAction `xml:"action"`
+	XmlNameNode `yaml:",inline"`
+	XMLName     xml.Name    `xml:"controller" yaml:"-"`
+	Actions     []XmlAction `xml:"action" yaml:"actions"`
 }
 
 type XmlControllers struct {
-	XmlNameNode
-	XMLName     xml.Name        `xml:"controllers"`
-	Controllers []XmlController `xml:"controller"`
+	XmlNameNode `yaml:",inline"`
+	XMLName     xml.Name        `xml:"controllers" yaml:"-"`
+	Controllers []XmlController `xml:"controller" yaml:"controllers"`
 }
 
 // FindQuery reads an XML file containing controller and action data,
@@ -36,39 +178,50 @@ type XmlControllers struct {
 // It takes the following arguments:
 // - controller: the name of the XML file (without the extension) to be read
 // - action: the name of the action to find in the XML file
-// The function returns the query string associated with the given controller and action,
-// or an empty string if the XML file cannot be read or the controller and action cannot be found.
+// The function returns the query string associated with the given controller and action for the dialect set by SetDialect,
+// or an empty string if the XML file cannot be read, the controller and action cannot be found, or it has no text for the dialect.
 func FindQuery(controller string, action string) string {
-	// Load the XML file into the XmlControllers struct.
-	controllers := XmlControllers{}
-	err := loadXml(&controllers, controller)
+	act, err := findAction(controller, action)
 
-	// Return an empty string if there was an error loading the XML file.
+	// Return an empty string if the XML file cannot be read or the action cannot be found.
 	if err != nil {
 		return ""
 	}
 
-	// Return an empty string if there are no controllers in the XmlControllers struct.
-	if len(controllers.Controllers) < 1 {
+	// Return an empty string if the action has no text for the dialect.
+	result, err := act.FindText(dialect)
+	if err != nil {
 		return ""
 	}
 
-	// Iterate through each controller in the XmlControllers struct.
-	for _, contr := range controllers.Controllers {
-		// If the controller name matches the one we're looking for, or if it's the "Base" controller...
-		if contr.Name == controller || contr.Name == "Base" {
-			// Iterate through each action in the controller.
-			for _, act := range contr.Actions {
-				// If the action name matches the one we're looking for, return the query string.
-				if act.Name == action {
-					return act.Text
-				}
-			}
-		}
+	return result
+}
+
+// FindQueryTemplate reads an XML file containing controller and action data,
+// and compiles the query text of a given controller and action, for the dialect set by SetDialect,
+// against its declared <param> nodes.
+// It takes the following arguments:
+// - controller: the name of the XML file (without the extension) to be read
+// - action: the name of the action to find in the XML file
+// The function returns ERROR_ACTION_NOT_FOUND if the controller and action cannot be found or have no text for the dialect,
+// or the compilation error if the query text uses unknown placeholders.
+func FindQueryTemplate(controller string, action string) (*QueryTemplate, error) {
+	act, err := findAction(controller, action)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := act.FindText(dialect)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", controller, action, err)
+	}
+
+	result, err := ParseQueryTemplate(text, act.Params...)
+	if err != nil {
+		return nil, fmt.Errorf("%s/%s: %w", controller, action, err)
 	}
 
-	// Return an empty string if the controller and action couldn't be found.
-	return ""
+	return result, nil
 }
 
 // FindQueryWithinParam reads an XML file containing controller and action data,
@@ -80,15 +233,20 @@ func FindQuery(controller string, action string) string {
 // - queryParam: the value to replace the "[QUERY_PARAMS]" placeholder string with
 // The function returns the resulting query string,
 // or an empty string if the XML file cannot be read or the controller and action cannot be found.
+// A query text that does not compile or render as a QueryTemplate, e.g. a legacy text using required params,
+// gets the "[QUERY_PARAMS]" placeholder replaced as a plain string, as before the templates.
 func FindQueryWithinParam(controller string, action string, queryParam string) string {
-	// Get the query string associated with the given controller and action.
-	result := FindQuery(controller, action)
-
-	// Replace the "[QUERY_PARAMS]" placeholder string with the given queryParam.
-	result = strings.ReplaceAll(result, "[QUERY_PARAMS]", queryParam)
+	// Get the compiled query template associated with the given controller and action,
+	// and render the "[QUERY_PARAMS]" placeholder with the given queryParam, leaving the other legacy placeholders untouched.
+	template, err := FindQueryTemplate(controller, action)
+	if err == nil {
+		if result, err := template.Render(TemplateValues{}.Set(TEMPLATE_QUERY_PARAMS, queryParam)); err == nil {
+			return result
+		}
+	}
 
-	// Return the resulting query string.
-	return result
+	// Otherwise replace the "[QUERY_PARAMS]" placeholder string in the query string, empty if the action cannot be found.
+	return strings.ReplaceAll(FindQuery(controller, action), "["+TEMPLATE_QUERY_PARAMS+"]", queryParam)
 }
 
 // FindQueryWithinParamAndUser reads an XML file containing controller and action data,
@@ -140,26 +298,45 @@ func FindQueryWithinUser[T any](controller string, action string, user T, replac
 	return result
 }
 
-// loadXml reads an XML file at a given path and unmarshals its contents into an interface.
-// It takes the following arguments:
-// - result: a pointer to an interface that will hold the unmarshalled data
-// - controller: the name of the XML file (without the extension) to be read
-// The function returns an error if it fails to read or unmarshal the XML file.
-func loadXml(result interface{}, controller string) error {
-	// Get the current working directory.
-	path, err := os.Getwd()
+// findAction reads an XML file containing controller and action data,
+// and returns the action matching the given controller and action.
+// Actions of the "Base" controller are shared by every controller of the file.
+// The function returns ERROR_ACTION_NOT_FOUND if the controller and action cannot be found.
+func findAction(controller string, action string) (*XmlAction, error) {
+	// Load the catalog of the controller, whatever its format.
+	controllers, err := LoadCatalog(controller)
 	if err != nil {
-		return err
+		// A controller without catalog has no action at all.
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ERROR_ACTION_NOT_FOUND
+		}
+		return nil, err
 	}
 
-	// Remove certain paths from the working directory.
-	for _, e := range REMOVE_PATHS {
-		path = strings.ReplaceAll(path, e, "")
+	// Iterate through each controller in the XmlControllers struct.
+	for _, contr := range controllers.Controllers {
+		// If the controller name matches the one we're looking for, or if it's the "Base" controller...
+		if contr.Name == controller || contr.Name == "Base" {
+			// Iterate through each action in the controller.
+			for i := range contr.Actions {
+				// If the action name matches the one we're looking for, return the action.
+				if contr.Actions[i].Name == action {
+					return &contr.Actions[i], nil
+				}
+			}
+		}
 	}
 
-	// Construct the path to the XML file.
-	filePath := fmt.Sprintf("%s/xml/%s.xml", path, controller)
+	// Return ERROR_ACTION_NOT_FOUND if the controller and action couldn't be found.
+	return nil, ERROR_ACTION_NOT_FOUND
+}
 
+// loadXml reads an XML file at a given path and unmarshals its contents into an interface.
+// It takes the following arguments:
+// - result: a pointer to an interface that will hold the unmarshalled data
+// - filePath: the path of the XML file to be read
+// The function returns an error if it fails to read or unmarshal the XML file.
+func loadXml(result interface{}, filePath string) error {
 	// Read the contents of the XML file.
 	xmlBytes, err := os.ReadFile(filePath)
 	if err != nil {
@@ -167,8 +344,5 @@ func loadXml(result interface{}, controller string) error {
 	}
 
 	// Unmarshal the XML into the provided result interface.
-	xml.Unmarshal(xmlBytes, result)
-
-	// Return nil if everything succeeded.
-	return nil
+	return xml.Unmarshal(xmlBytes, result)
 }
@@ -0,0 +1,573 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	TEMPLATE_TYPE_RAW      string = "raw"
+	TEMPLATE_TYPE_INT      string = "int"
+	TEMPLATE_TYPE_BIGINT   string = "bigint"
+	TEMPLATE_TYPE_BIT      string = "bit"
+	TEMPLATE_TYPE_DECIMAL  string = "decimal"
+	TEMPLATE_TYPE_VARCHAR  string = "varchar"
+	TEMPLATE_TYPE_NVARCHAR string = "nvarchar"
+	TEMPLATE_TYPE_DATE     string = "date"
+	TEMPLATE_TYPE_DATETIME string = "datetime"
+	TEMPLATE_TYPE_GUID     string = "guid"
+
+	TEMPLATE_QUERY_PARAMS    string = "QUERY_PARAMS"
+	TEMPLATE_LANGUAGE_SUFFIX string = "LANGUAGE_SUFFIX"
+)
+
+// TEMPLATE_TYPE_ALIASES maps alternative type names accepted in <param type="..."/> to their canonical type.
+var TEMPLATE_TYPE_ALIASES = map[string]string{
+	"":                 TEMPLATE_TYPE_NVARCHAR,
+	"sql":              TEMPLATE_TYPE_RAW,
+	"smallint":         TEMPLATE_TYPE_INT,
+	"long":             TEMPLATE_TYPE_BIGINT,
+	"bool":             TEMPLATE_TYPE_BIT,
+	"boolean":          TEMPLATE_TYPE_BIT,
+	"numeric":          TEMPLATE_TYPE_DECIMAL,
+	"float":            TEMPLATE_TYPE_DECIMAL,
+	"string":           TEMPLATE_TYPE_NVARCHAR,
+	"text":             TEMPLATE_TYPE_NVARCHAR,
+	"uniqueidentifier": TEMPLATE_TYPE_GUID,
+	"uuid":             TEMPLATE_TYPE_GUID,
+}
+
+var (
+	templateTokenPattern = regexp.MustCompile(`\{\{\s*([#^/]?)\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}|\[QUERY_PARAMS\]|\[2\]|@@[A-Za-z_][A-Za-z0-9_]*`)
+	templateGuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	templateTimestampType = reflect.TypeOf((*timestamppb.Timestamp)(nil)).Elem()
+)
+
+// TemplateValues holds the values used to render a QueryTemplate, keyed by placeholder name.
+// Lookups are case-insensitive, so values bound from a struct field "FromDate" satisfy {{fromDate}}.
+type TemplateValues map[string]interface{}
+
+// Set stores a value under the given placeholder name.
+func (v TemplateValues) Set(name string, value interface{}) TemplateValues {
+	v[strings.ToLower(name)] = value
+	return v
+}
+
+// Get returns the value stored under the given placeholder name, and whether it exists.
+func (v TemplateValues) Get(name string) (interface{}, bool) {
+	value, ok := v[strings.ToLower(name)]
+	return value, ok
+}
+
+// Bind copies the exported fields of a struct (or pointer to struct) into the values.
+// Fields listed in IGNORE_FIELDS are skipped, and a nil model binds nothing.
+func (v TemplateValues) Bind(model interface{}) TemplateValues {
+	if model == nil {
+		return v
+	}
+
+	rfValue := reflect.ValueOf(model)
+	for rfValue.Kind() == reflect.Ptr {
+		if rfValue.IsNil() {
+			return v
+		}
+		rfValue = rfValue.Elem()
+	}
+
+	if rfValue.Kind() != reflect.Struct {
+		return v
+	}
+
+	rfType := rfValue.Type()
+	for _, i := range findFieldsUsedIndex(rfType, IGNORE_FIELDS...) {
+		field := rfType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		v.Set(field.Name, rfValue.Field(i).Interface())
+	}
+
+	return v
+}
+
+type templateNodeKind int
+
+const (
+	templateNodeText templateNodeKind = iota
+	templateNodePlaceholder
+	templateNodeSection
+	templateNodeClaim
+)
+
+type templateNode struct {
+	kind     templateNodeKind
+	text     string // literal text, or the original token for a placeholder
+	name     string
+	legacy   bool // [QUERY_PARAMS] and [2] keep their token when no value is supplied
+	inverted bool
+	children []templateNode
+}
+
+// QueryTemplate is a compiled query catalog text.
+//
+// It understands the following syntax:
+//   - {{name}}: a named placeholder, declared in the catalog with <param name="name" type="..."/>
+//   - {{#name}} ... {{/name}}: a section rendered only when "name" has a non-zero value
+//   - {{^name}} ... {{/name}}: a section rendered only when "name" has no value
+//   - [QUERY_PARAMS] and [2]: the legacy placeholders, which are always known
+//   - @@claim: a claim placeholder, rendered by the IClaimRenderer registered with RegisterClaim
+//
+// The @@ tokens inside a string literal or a comment, and the SQL_GLOBALS, are plain SQL, never claims.
+type QueryTemplate struct {
+	nodes    []templateNode
+	params   map[string]XmlParam
+	lexState sqlLexState // while parsing, where the text parsed so far ends
+}
+
+// ParseQueryTemplate compiles a catalog text against its declared parameters.
+// It returns an error if the text uses a placeholder that is neither declared nor built in,
+// or if a section is not closed properly.
+// The @@ placeholders are not checked against the registered claims: an unknown claim is only an error
+// when the template is rendered with claims, see UnknownClaims.
+func ParseQueryTemplate(text string, params ...XmlParam) (*QueryTemplate, error) {
+	result := &QueryTemplate{params: map[string]XmlParam{}}
+
+	for _, e := range builtinTemplateParams() {
+		result.params[strings.ToLower(e.Name)] = e
+	}
+
+	for _, e := range params {
+		if e.Name == "" {
+			return nil, fmt.Errorf("query_template: parameter without name")
+		}
+		if _, ok := TEMPLATE_TYPE_ALIASES[e.Type]; !ok && !isTemplateType(e.Type) {
+			return nil, fmt.Errorf("query_template: unknown type %q for parameter %q", e.Type, e.Name)
+		}
+		result.params[strings.ToLower(e.Name)] = e
+	}
+
+	nodes, rest, err := result.parse(text, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if rest != "" {
+		return nil, fmt.Errorf("query_template: unexpected text after template end")
+	}
+
+	result.nodes = nodes
+	return result, nil
+}
+
+// Placeholders returns the names of all the placeholders and sections used in the template, in order of appearance.
+func (t *QueryTemplate) Placeholders() []string {
+	return t.findNames(templateNodePlaceholder, templateNodeSection)
+}
+
+// Claims returns the names of all the @@ claims used in the template, in order of appearance.
+func (t *QueryTemplate) Claims() []string {
+	return t.findNames(templateNodeClaim)
+}
+
+// UnknownClaims returns the names of the @@ claims used in the template that have no registered IClaimRenderer.
+// They render untouched without claims, and fail with ERROR_UNKNOWN_CLAIM when rendered with claims.
+func (t *QueryTemplate) UnknownClaims() []string {
+	result := []string{}
+	for _, e := range t.Claims() {
+		if FindClaimRenderer(e) == nil {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Render renders the template with the given values, leaving the @@ and [2] placeholders untouched.
+// Missing named placeholders render as null, unless they are declared as required.
+func (t *QueryTemplate) Render(values TemplateValues) (string, error) {
+	return t.RenderWithClaims(values, nil)
+}
+
+// RenderWithClaims renders the template with the given values and claims, writing the claims into the query text.
+// The @@ placeholders are rendered by their registered IClaimRenderer, whose declarations are written before the query,
+// and [2] is rendered as the language suffix of the claims.
+// A nil claims leaves the @@ and [2] placeholders untouched.
+func (t *QueryTemplate) RenderWithClaims(values TemplateValues, claims IClaims) (string, error) {
+	result, _, err := t.RenderWithClaimsMode(values, claims, CLAIMS_MODE_TEXT)
+	return result, err
+}
+
+// RenderWithClaimsMode renders the template like RenderWithClaims, passing the claims as set by the given CLAIMS_MODE_*.
+// It returns the query, and the parameters of the claims, which must come first in the parameters of the query.
+func (t *QueryTemplate) RenderWithClaimsMode(values TemplateValues, claims IClaims, mode string) (string, []interface{}, error) {
+	var rendered *renderedClaims
+	builder := &strings.Builder{}
+
+	if claims != nil {
+		var err error
+		if rendered, err = renderClaims(t.Claims(), claims, mode); err != nil {
+			return "", nil, fmt.Errorf("query_template: %w", err)
+		}
+
+		builder.WriteString(rendered.prelude)
+
+		copied := TemplateValues{}
+		for k, v := range values {
+			copied[k] = v
+		}
+		values = copied.Set(TEMPLATE_LANGUAGE_SUFFIX, IIF(claims.GetIsBaseLanguage(), "", "2"))
+	}
+
+	if err := t.render(builder, t.nodes, values, rendered); err != nil {
+		return "", nil, err
+	}
+
+	if rendered == nil {
+		return builder.String(), nil, nil
+	}
+	return builder.String(), rendered.args, nil
+}
+
+func (t *QueryTemplate) findNames(kinds ...templateNodeKind) []string {
+	result := []string{}
+	walkTemplateNodes(t.nodes, func(node templateNode) {
+		if ComparableContains(node.kind, kinds...) && !ComparableContains(node.name, result...) {
+			result = append(result, node.name)
+		}
+	})
+	return result
+}
+
+func (t *QueryTemplate) parse(text string, section string) ([]templateNode, string, error) {
+	nodes := []templateNode{}
+
+	for {
+		loc := templateTokenPattern.FindStringSubmatchIndex(text)
+		if loc == nil {
+			if section != "" {
+				return nil, "", fmt.Errorf("query_template: section %q is not closed", section)
+			}
+			if text != "" {
+				nodes = t.appendText(nodes, text)
+			}
+			return nodes, "", nil
+		}
+
+		if loc[0] > 0 {
+			nodes = t.appendText(nodes, text[:loc[0]])
+		}
+
+		token := text[loc[0]:loc[1]]
+		marker, name := "", ""
+		if loc[4] >= 0 {
+			marker, name = text[loc[2]:loc[3]], text[loc[4]:loc[5]]
+		}
+		text = text[loc[1]:]
+
+		switch {
+		case token == "[QUERY_PARAMS]":
+			nodes = append(nodes, templateNode{kind: templateNodePlaceholder, text: token, name: TEMPLATE_QUERY_PARAMS, legacy: true})
+		case token == "[2]":
+			nodes = append(nodes, templateNode{kind: templateNodePlaceholder, text: token, name: TEMPLATE_LANGUAGE_SUFFIX, legacy: true})
+		case strings.HasPrefix(token, "@@"):
+			name := token[2:]
+			if t.lexState != sqlLexCode || (FindClaimRenderer(name) == nil && IsSqlGlobal(name)) {
+				// Not a claim, e.g. @@ROWCOUNT, a string literal or a comment: keep it as plain SQL.
+				nodes = t.appendText(nodes, token)
+				continue
+			}
+			nodes = append(nodes, templateNode{kind: templateNodeClaim, text: token, name: name})
+		default:
+			if marker != "/" {
+				if _, ok := t.params[strings.ToLower(name)]; !ok {
+					return nil, "", fmt.Errorf("query_template: unknown placeholder %q", name)
+				}
+			}
+
+			switch marker {
+			case "/":
+				if !strings.EqualFold(name, section) {
+					return nil, "", fmt.Errorf("query_template: unexpected end of section %q", name)
+				}
+				return nodes, text, nil
+			case "#", "^":
+				children, rest, err := t.parse(text, name)
+				if err != nil {
+					return nil, "", err
+				}
+				nodes = append(nodes, templateNode{kind: templateNodeSection, text: token, name: name, inverted: marker == "^", children: children})
+				text = rest
+			default:
+				nodes = append(nodes, templateNode{kind: templateNodePlaceholder, text: token, name: name})
+			}
+		}
+	}
+}
+
+// appendText adds a literal text node, and tracks whether the parsed text ends in a string literal or a comment.
+func (t *QueryTemplate) appendText(nodes []templateNode, text string) []templateNode {
+	t.lexState = t.lexState.scan(text)
+	return append(nodes, templateNode{kind: templateNodeText, text: text})
+}
+
+// sqlLexState is where a SQL text ends: in the code, a string literal, a line comment or a block comment.
+type sqlLexState int
+
+const (
+	sqlLexCode sqlLexState = iota
+	sqlLexString
+	sqlLexLineComment
+	sqlLexBlockComment
+)
+
+// scan returns the state at the end of a text starting in the state s.
+// An escaped quote ” leaves and enters the string literal again, and the quotes of the comments are ignored.
+func (s sqlLexState) scan(text string) sqlLexState {
+	for i := 0; i < len(text); i++ {
+		switch s {
+		case sqlLexCode:
+			switch {
+			case text[i] == '\'':
+				s = sqlLexString
+			case strings.HasPrefix(text[i:], "--"):
+				s, i = sqlLexLineComment, i+1
+			case strings.HasPrefix(text[i:], "/*"):
+				s, i = sqlLexBlockComment, i+1
+			}
+		case sqlLexString:
+			if text[i] == '\'' {
+				s = sqlLexCode
+			}
+		case sqlLexLineComment:
+			if text[i] == '\n' {
+				s = sqlLexCode
+			}
+		case sqlLexBlockComment:
+			if strings.HasPrefix(text[i:], "*/") {
+				s, i = sqlLexCode, i+1
+			}
+		}
+	}
+	return s
+}
+
+func (t *QueryTemplate) render(builder *strings.Builder, nodes []templateNode, values TemplateValues, claims *renderedClaims) error {
+	for _, node := range nodes {
+		switch node.kind {
+		case templateNodeText:
+			builder.WriteString(node.text)
+		case templateNodeClaim:
+			if claims == nil {
+				builder.WriteString(node.text)
+				continue
+			}
+			builder.WriteString(claims.expressions[node.name])
+		case templateNodeSection:
+			value, _ := values.Get(node.name)
+			if isTemplateValueSet(value) != node.inverted {
+				if err := t.render(builder, node.children, values, claims); err != nil {
+					return err
+				}
+			}
+		case templateNodePlaceholder:
+			param := t.params[strings.ToLower(node.name)]
+			value, ok := values.Get(node.name)
+			if !ok && node.legacy {
+				builder.WriteString(node.text)
+				continue
+			}
+
+			if param.Required && !(ok && isTemplateValuePresent(value)) {
+				return fmt.Errorf("query_template: missing value for required parameter %q", param.Name)
+			}
+
+			text, err := RenderTemplateValue(param.Type, value)
+			if err != nil {
+				return fmt.Errorf("query_template: parameter %q: %w", param.Name, err)
+			}
+			builder.WriteString(text)
+		}
+	}
+
+	return nil
+}
+
+// RenderTemplateValue converts a Go value to a SQL literal of the given parameter type.
+// nil values and nil pointers render as null.
+func RenderTemplateValue(paramType string, value interface{}) (string, error) {
+	rfValue := reflect.ValueOf(value)
+	for rfValue.Kind() == reflect.Ptr {
+		if rfValue.IsNil() {
+			return "null", nil
+		}
+		if stamp, ok := rfValue.Interface().(*timestamppb.Timestamp); ok {
+			rfValue = reflect.ValueOf(stamp.AsTime())
+			break
+		}
+		rfValue = rfValue.Elem()
+	}
+
+	if !rfValue.IsValid() {
+		return "null", nil
+	}
+
+	if rfValue.Type() == templateTimestampType {
+		stamp := reflect.New(templateTimestampType)
+		stamp.Elem().Set(rfValue)
+		rfValue = reflect.ValueOf(stamp.Interface().(*timestamppb.Timestamp).AsTime())
+	}
+
+	value = rfValue.Interface()
+	if alias, ok := TEMPLATE_TYPE_ALIASES[paramType]; ok {
+		paramType = alias
+	}
+
+	switch paramType {
+	case TEMPLATE_TYPE_RAW:
+		return fmt.Sprint(value), nil
+	case TEMPLATE_TYPE_INT, TEMPLATE_TYPE_BIGINT:
+		return toTemplateInt(rfValue)
+	case TEMPLATE_TYPE_BIT:
+		return toTemplateBit(rfValue)
+	case TEMPLATE_TYPE_DECIMAL:
+		return toTemplateDecimal(rfValue)
+	case TEMPLATE_TYPE_VARCHAR:
+		return fmt.Sprintf("'%s'", Safe(fmt.Sprint(value))), nil
+	case TEMPLATE_TYPE_NVARCHAR:
+		return fmt.Sprintf("N'%s'", Safe(fmt.Sprint(value))), nil
+	case TEMPLATE_TYPE_DATE:
+		return toTemplateTime(value, STRING_FORMAT_DATE_SHORT)
+	case TEMPLATE_TYPE_DATETIME:
+		return toTemplateTime(value, STRING_FORMAT_DATE_LONG)
+	case TEMPLATE_TYPE_GUID:
+		text := fmt.Sprint(value)
+		if !templateGuidPattern.MatchString(text) {
+			return "", fmt.Errorf("invalid guid %q", text)
+		}
+		return fmt.Sprintf("'%s'", text), nil
+	default:
+		return "", fmt.Errorf("unknown type %q", paramType)
+	}
+}
+
+// builtinTemplateParams returns the placeholders every template knows without declaring them:
+// the [QUERY_PARAMS] script and the [2] language suffix.
+func builtinTemplateParams() []XmlParam {
+	return []XmlParam{
+		{Name: TEMPLATE_QUERY_PARAMS, Type: TEMPLATE_TYPE_RAW},
+		{Name: TEMPLATE_LANGUAGE_SUFFIX, Type: TEMPLATE_TYPE_RAW},
+	}
+}
+
+func isTemplateType(paramType string) bool {
+	return ComparableContains(paramType, TEMPLATE_TYPE_RAW, TEMPLATE_TYPE_INT, TEMPLATE_TYPE_BIGINT, TEMPLATE_TYPE_BIT,
+		TEMPLATE_TYPE_DECIMAL, TEMPLATE_TYPE_VARCHAR, TEMPLATE_TYPE_NVARCHAR, TEMPLATE_TYPE_DATE, TEMPLATE_TYPE_DATETIME, TEMPLATE_TYPE_GUID)
+}
+
+// isTemplateValuePresent reports whether a value is given to a required parameter: it must be non-nil,
+// so the zero values, e.g. 0, false or "", are valid values.
+func isTemplateValuePresent(value interface{}) bool {
+	rfValue := reflect.ValueOf(value)
+	for rfValue.Kind() == reflect.Ptr || rfValue.Kind() == reflect.Interface {
+		if rfValue.IsNil() {
+			return false
+		}
+		rfValue = rfValue.Elem()
+	}
+
+	if ComparableContains(rfValue.Kind(), reflect.Slice, reflect.Map) {
+		return !rfValue.IsNil()
+	}
+
+	return rfValue.IsValid()
+}
+
+// isTemplateValueSet reports whether a value turns a {{#name}} section on: it must be non-nil and non-zero.
+func isTemplateValueSet(value interface{}) bool {
+	rfValue := reflect.ValueOf(value)
+	for rfValue.Kind() == reflect.Ptr {
+		if rfValue.IsNil() {
+			return false
+		}
+		rfValue = rfValue.Elem()
+	}
+
+	if !rfValue.IsValid() {
+		return false
+	}
+
+	if ComparableContains(rfValue.Kind(), reflect.Slice, reflect.Map) {
+		return rfValue.Len() > 0
+	}
+
+	return !rfValue.IsZero()
+}
+
+func walkTemplateNodes(nodes []templateNode, visit func(templateNode)) {
+	for _, node := range nodes {
+		visit(node)
+		walkTemplateNodes(node.children, visit)
+	}
+}
+
+func toTemplateInt(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.String:
+		if _, err := strconv.ParseInt(value.String(), 10, 64); err != nil {
+			return "", fmt.Errorf("invalid integer %q", value.String())
+		}
+		return value.String(), nil
+	default:
+		return "", fmt.Errorf("cannot render %s as integer", value.Type())
+	}
+}
+
+func toTemplateBit(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.Bool:
+		return strconv.Itoa(BoolToInt(value.Bool())), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.Itoa(BoolToInt(value.Int() != 0)), nil
+	default:
+		return "", fmt.Errorf("cannot render %s as bit", value.Type())
+	}
+}
+
+func toTemplateDecimal(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), nil
+	case reflect.String:
+		if _, err := strconv.ParseFloat(value.String(), 64); err != nil {
+			return "", fmt.Errorf("invalid decimal %q", value.String())
+		}
+		return value.String(), nil
+	default:
+		return toTemplateInt(value)
+	}
+}
+
+func toTemplateTime(value interface{}, format string) (string, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return fmt.Sprintf("'%s'", v.Format(format)), nil
+	case string:
+		t, err := time.Parse(format, v)
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q", v)
+		}
+		return fmt.Sprintf("'%s'", t.Format(format)), nil
+	default:
+		return "", fmt.Errorf("cannot render %T as date", value)
+	}
+}
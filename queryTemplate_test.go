@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseQueryTemplateUnknownClaims(t *testing.T) {
+	template, err := ParseQueryTemplate("select @@MICROSOFTVERSION, '@@literal' as Name, * from Orders where RegionID = @@regionID and UserID = @@userID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if unknowns := template.UnknownClaims(); len(unknowns) != 1 || unknowns[0] != "regionID" {
+		t.Errorf("unknown claims are %v", unknowns)
+	}
+
+	query, err := template.Render(TemplateValues{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "select @@MICROSOFTVERSION, '@@literal' as Name, * from Orders where RegionID = @@regionID and UserID = @@userID"; query != expected {
+		t.Errorf("query is %q", query)
+	}
+
+	_, err = template.RenderWithClaims(TemplateValues{}, &Claims{Id: 42})
+	if !errors.Is(err, ERROR_UNKNOWN_CLAIM) || !strings.Contains(err.Error(), "@@regionID") {
+		t.Errorf("rendering with claims returned %v", err)
+	}
+}
+
+func TestParseQueryTemplateStringLiterals(t *testing.T) {
+	template, err := ParseQueryTemplate("select 'it''s @@userID' as A, @@userID as B, '@@userID' as C")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := template.RenderWithClaims(TemplateValues{}, &Claims{Id: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "select 'it''s @@userID' as A, 42 as B, '@@userID' as C"; query != expected {
+		t.Errorf("query is %q", query)
+	}
+}
+
+func TestQueryTemplateRequiredAcceptsZeroValues(t *testing.T) {
+	template, err := ParseQueryTemplate("select * from Orders where Amount = {{amount}} and IsPaid = {{isPaid}}{{#note}} and Note = {{note}}{{/note}}",
+		XmlParam{Name: "amount", Type: "int", Required: true}, XmlParam{Name: "isPaid", Type: "bool", Required: true}, XmlParam{Name: "note", Type: "string"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := template.Render(TemplateValues{}.Set("amount", 0).Set("isPaid", false).Set("note", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "select * from Orders where Amount = 0 and IsPaid = 0"; query != expected {
+		t.Errorf("query is %q", query)
+	}
+
+	var amount *int
+	for _, values := range []TemplateValues{TemplateValues{}.Set("isPaid", true), TemplateValues{}.Set("amount", amount).Set("isPaid", true)} {
+		if _, err := template.Render(values); err == nil || !strings.Contains(err.Error(), `"amount"`) {
+			t.Errorf("rendering %v returned %v", values, err)
+		}
+	}
+}
+
+func TestParseQueryTemplateComments(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"-- user's orders\nselect * from Orders where UserID = @@userID", "-- user's orders\nselect * from Orders where UserID = 42"},
+		{"/* the user's\n orders */ select * from Orders where UserID = @@userID -- @@userID", "/* the user's\n orders */ select * from Orders where UserID = 42 -- @@userID"},
+		{"select '--' as A, '/*' as B, @@userID as C", "select '--' as A, '/*' as B, 42 as C"},
+	}
+
+	for _, test := range tests {
+		template, err := ParseQueryTemplate(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, err := template.RenderWithClaims(TemplateValues{}, &Claims{Id: 42})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != test.expected {
+			t.Errorf("query is %q, expected %q", query, test.expected)
+		}
+	}
+}
+
+func TestFindQueryWithinParamFallsBackToReplace(t *testing.T) {
+	dir := t.TempDir()
+	catalog := `<controllers>
+	<controller name="Orders">
+		<action name="Search"><text>select * from Orders [QUERY_PARAMS] where 1 = 1</text></action>
+		<action name="Required"><param name="id" type="int" required="true"/><text>select * from Orders [QUERY_PARAMS] where Id = {{id}}</text></action>
+		<action name="Invalid"><text>select {{unknown}} [QUERY_PARAMS]</text></action>
+	</controller>
+</controllers>`
+	if err := os.MkdirAll(filepath.Join(dir, "xml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "xml", "Orders.xml"), []byte(catalog), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := map[string]string{
+		"Search":   "select * from Orders declare @$Model table(Id int) where 1 = 1",
+		"Required": "select * from Orders declare @$Model table(Id int) where Id = {{id}}",
+		"Invalid":  "select {{unknown}} declare @$Model table(Id int)",
+		"Missing":  "",
+	}
+	for action, expected := range tests {
+		if query := FindQueryWithinParam("Orders", action, "declare @$Model table(Id int)"); query != expected {
+			t.Errorf("query of %s is %q, expected %q", action, query, expected)
+		}
+	}
+}
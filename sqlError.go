@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// The numbers of the SQL Server errors classified by ClassifySqlError.
+const (
+	SQL_ERROR_NUMBER_UNIQUE_CONSTRAINT int32 = 2627
+	SQL_ERROR_NUMBER_UNIQUE_INDEX      int32 = 2601
+	SQL_ERROR_NUMBER_CONSTRAINT        int32 = 547
+	SQL_ERROR_NUMBER_DEADLOCK          int32 = 1205
+	SQL_ERROR_NUMBER_TRUNCATION        int32 = 8152
+	SQL_ERROR_NUMBER_TRUNCATION_COLUMN int32 = 2628
+
+	// SQL_ERROR_NUMBER_USER is the first number of the errors raised by RAISERROR and THROW.
+	SQL_ERROR_NUMBER_USER int32 = 50000
+)
+
+// sqlErrorKinds maps the codes of the PostgreSQL and MySQL errors, see SqlError.Code, to their kind.
+// The violations of a foreign key by a delete are told apart by their message.
+var sqlErrorKinds = map[string]map[string]error{
+	SQL_DRIVER_POSTGRES: {
+		"23505": ERROR_SQL_UNIQUE_VIOLATION,
+		"23503": ERROR_SQL_FOREIGN_KEY_VIOLATION,
+		"23514": ERROR_SQL_CHECK_VIOLATION,
+		"40P01": ERROR_SQL_DEADLOCK,
+		"22001": ERROR_SQL_TRUNCATION,
+		"P0001": ERROR_SQL_USER,
+	},
+	SQL_DRIVER_MYSQL: {
+		"1062": ERROR_SQL_UNIQUE_VIOLATION,
+		"1452": ERROR_SQL_FOREIGN_KEY_VIOLATION,
+		"1451": ERROR_SQL_REFERENCE_VIOLATION,
+		"3819": ERROR_SQL_CHECK_VIOLATION,
+		"1213": ERROR_SQL_DEADLOCK,
+		"1406": ERROR_SQL_TRUNCATION,
+		"1644": ERROR_SQL_USER,
+	},
+}
+
+// The kinds of the SQL errors, matched with errors.Is, which are also their codes in the error catalog.
+var (
+	ERROR_SQL_UNIQUE_VIOLATION      = errors.New("sql_unique_violation")
+	ERROR_SQL_FOREIGN_KEY_VIOLATION = errors.New("sql_foreign_key_violation")
+	ERROR_SQL_REFERENCE_VIOLATION   = errors.New("sql_reference_violation")
+	ERROR_SQL_CHECK_VIOLATION       = errors.New("sql_check_violation")
+	ERROR_SQL_DEADLOCK              = errors.New("sql_deadlock")
+	ERROR_SQL_TRUNCATION            = errors.New("sql_truncation")
+	ERROR_SQL_USER                  = errors.New("sql_user_error")
+)
+
+var (
+	sqlConstraintPattern   = regexp.MustCompile(`(?i)(FOREIGN KEY|REFERENCE|CHECK|PRIMARY KEY|UNIQUE KEY) constraint ["']([^"']+)["']`)
+	sqlIndexPattern        = regexp.MustCompile(`(?i)unique index '([^']+)'`)
+	sqlObjectPattern       = regexp.MustCompile(`(?i)(?:object|table) ["']([^"']+)["']`)
+	sqlColumnPattern       = regexp.MustCompile(`(?i)column '([^']+)'`)
+	sqlDuplicateKeyPattern = regexp.MustCompile(`(?i)duplicate key value is \((.*)\)`)
+
+	postgresReferencePattern  = regexp.MustCompile(`(?i)^update or delete on table`)
+	mysqlDuplicateKeyPattern  = regexp.MustCompile(`(?i)duplicate entry '(.*)' for key '([^']+)'`)
+	mysqlConstraintPattern    = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+	postgresDuplicateKeyValue = regexp.MustCompile(`(?i)\(.*\)=\((.*)\)`)
+)
+
+func init() {
+	for _, e := range []struct {
+		err        error
+		httpStatus int
+		grpcCode   codes.Code
+		severity   string
+		message    string
+	}{
+		{ERROR_SQL_UNIQUE_VIOLATION, http.StatusConflict, codes.AlreadyExists, ERROR_SEVERITY_WARNING, "The record already exists."},
+		{ERROR_SQL_FOREIGN_KEY_VIOLATION, http.StatusBadRequest, codes.FailedPrecondition, ERROR_SEVERITY_WARNING, "The referenced record does not exist."},
+		{ERROR_SQL_REFERENCE_VIOLATION, http.StatusConflict, codes.FailedPrecondition, ERROR_SEVERITY_WARNING, "The record is used by other records."},
+		{ERROR_SQL_CHECK_VIOLATION, http.StatusBadRequest, codes.FailedPrecondition, ERROR_SEVERITY_WARNING, "The record breaks the constraint {constraint}."},
+		{ERROR_SQL_DEADLOCK, http.StatusConflict, codes.Aborted, ERROR_SEVERITY_ERROR, "The request conflicted with another one, please retry."},
+		{ERROR_SQL_TRUNCATION, http.StatusBadRequest, codes.OutOfRange, ERROR_SEVERITY_WARNING, "A value is too long."},
+		{ERROR_SQL_USER, http.StatusBadRequest, codes.FailedPrecondition, ERROR_SEVERITY_WARNING, "{" + ERROR_PARAM_MESSAGE + "}"},
+	} {
+		RegisterErrorCode(&ErrorCode{Code: e.err.Error(), Err: e.err, HttpStatus: e.httpStatus, GrpcCode: e.grpcCode,
+			Severity: e.severity, Messages: map[string]string{ERROR_LANGUAGE_DEFAULT: e.message}})
+	}
+}
+
+// SqlDomainError is a SQL error classified by ClassifySqlError.
+// errors.Is matches both its kind, e.g. ERROR_SQL_UNIQUE_VIOLATION, and the original error.
+type SqlDomainError struct {
+	// Code is the code of the error in the catalog: the kind, or the code of the RAISERROR state, see RegisterSqlErrorStates.
+	Code string
+	Kind error
+
+	// Constraint, Table and Column are reported by the driver or read from the message, when it names them.
+	// Value is the duplicate key of a unique violation.
+	Constraint string
+	Table      string
+	Column     string
+	Value      string
+
+	// Sql is the SQL error, holding the number, state and message of the database.
+	Sql *SqlError
+}
+
+func (e *SqlDomainError) Error() string {
+	return e.Sql.Message
+}
+
+func (e *SqlDomainError) Unwrap() []error {
+	return []error{e.Kind, e.Sql}
+}
+
+func (e *SqlDomainError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+func (e *SqlDomainError) ErrorCode() string {
+	return e.Code
+}
+
+// ErrorParams returns the params of the message of the code: constraint, table, column, value and message.
+func (e *SqlDomainError) ErrorParams() map[string]string {
+	result := map[string]string{ERROR_PARAM_MESSAGE: e.Sql.Message}
+	for k, v := range map[string]string{"constraint": e.Constraint, "table": e.Table, "column": e.Column, "value": e.Value} {
+		if v != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// sqlErrorStateRange maps the states of the user errors to a code of the catalog.
+type sqlErrorStateRange struct {
+	from uint8
+	to   uint8
+	code string
+}
+
+var (
+	sqlErrorStates      []sqlErrorStateRange
+	sqlErrorStatesMutex sync.RWMutex
+)
+
+// RegisterSqlErrorStates maps the states from..to (inclusive) of the errors raised by RAISERROR or THROW
+// to a code of the catalog, so a procedure chooses the response of the service, e.g.
+//
+//	utils.RegisterErrorCode(&utils.ErrorCode{Code: "order_closed", HttpStatus: 409, GrpcCode: codes.FailedPrecondition})
+//	utils.RegisterSqlErrorStates(10, 19, "order_closed") // raiserror('The order is closed', 16, 10)
+//
+// The user errors of the other states get the code of ERROR_SQL_USER.
+func RegisterSqlErrorStates(from uint8, to uint8, code string) {
+	sqlErrorStatesMutex.Lock()
+	defer sqlErrorStatesMutex.Unlock()
+
+	sqlErrorStates = append(sqlErrorStates, sqlErrorStateRange{from: from, to: to, code: code})
+	sort.SliceStable(sqlErrorStates, func(i, j int) bool {
+		return sqlErrorStates[i].to-sqlErrorStates[i].from < sqlErrorStates[j].to-sqlErrorStates[j].from
+	})
+}
+
+// findSqlErrorState returns the code of the narrowest range holding the state, or an empty string.
+func findSqlErrorState(state uint8) string {
+	sqlErrorStatesMutex.RLock()
+	defer sqlErrorStatesMutex.RUnlock()
+
+	for _, e := range sqlErrorStates {
+		if state >= e.from && state <= e.to {
+			return e.code
+		}
+	}
+	return ""
+}
+
+// ClassifySqlError classifies a SQL error, as returned by ExtractSqlError, by its code:
+// the numbers of SQL Server, the SQLSTATE of PostgreSQL and the numbers of MySQL.
+// It returns nil if the error is not a SQL error or if its code is not classified.
+func ClassifySqlError(err error) *SqlDomainError {
+	var classified *SqlDomainError
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	sqlErr := ExtractSqlError(err)
+	if sqlErr == nil {
+		return nil
+	}
+
+	result := &SqlDomainError{Sql: sqlErr, Constraint: sqlErr.Constraint, Table: sqlErr.Table, Column: sqlErr.Column}
+	switch sqlErr.Driver {
+	case SQL_DRIVER_MSSQL:
+		classifyMssqlError(result)
+	case SQL_DRIVER_POSTGRES, SQL_DRIVER_MYSQL:
+		result.Kind = sqlErrorKinds[sqlErr.Driver][sqlErr.Code]
+	}
+
+	if result.Kind == nil {
+		return nil
+	}
+
+	if result.Code == "" {
+		result.Code = result.Kind.Error()
+	}
+
+	message := sqlErr.Message
+	if result.Kind == ERROR_SQL_FOREIGN_KEY_VIOLATION && postgresReferencePattern.MatchString(message) {
+		result.Kind = ERROR_SQL_REFERENCE_VIOLATION
+		result.Code = result.Kind.Error()
+	}
+
+	if m := mysqlDuplicateKeyPattern.FindStringSubmatch(message); m != nil {
+		result.Value, result.Constraint = m[1], m[2]
+	}
+	if m := sqlDuplicateKeyPattern.FindStringSubmatch(message); m != nil && result.Value == "" {
+		result.Value = m[1]
+	}
+	if sqlErr.Driver == SQL_DRIVER_POSTGRES && result.Kind == ERROR_SQL_UNIQUE_VIOLATION {
+		if m := postgresDuplicateKeyValue.FindStringSubmatch(reflectStringField(reflect.Indirect(reflect.ValueOf(sqlErr.Err)), "Detail")); m != nil {
+			result.Value = m[1]
+		}
+	}
+
+	if result.Constraint == "" {
+		if m := sqlIndexPattern.FindStringSubmatch(message); m != nil {
+			result.Constraint = m[1]
+		} else if m := sqlConstraintPattern.FindStringSubmatch(message); m != nil {
+			result.Constraint = m[2]
+		} else if m := mysqlConstraintPattern.FindStringSubmatch(message); m != nil {
+			result.Constraint = m[1]
+		}
+	}
+	if m := sqlObjectPattern.FindStringSubmatch(message); m != nil && result.Table == "" {
+		result.Table = m[1]
+	}
+	if m := sqlColumnPattern.FindStringSubmatch(message); m != nil && result.Column == "" {
+		result.Column = m[1]
+	}
+
+	return result
+}
+
+// classifyMssqlError sets the kind of a SQL Server error from its number, and the code of a RAISERROR from its state.
+func classifyMssqlError(result *SqlDomainError) {
+	number, _ := strconv.ParseInt(result.Sql.Code, 10, 32)
+
+	switch int32(number) {
+	case SQL_ERROR_NUMBER_UNIQUE_CONSTRAINT, SQL_ERROR_NUMBER_UNIQUE_INDEX:
+		result.Kind = ERROR_SQL_UNIQUE_VIOLATION
+	case SQL_ERROR_NUMBER_CONSTRAINT:
+		result.Kind = ERROR_SQL_CHECK_VIOLATION
+		if m := sqlConstraintPattern.FindStringSubmatch(result.Sql.Message); m != nil {
+			switch strings.ToUpper(m[1]) {
+			case "FOREIGN KEY":
+				result.Kind = ERROR_SQL_FOREIGN_KEY_VIOLATION
+			case "REFERENCE":
+				result.Kind = ERROR_SQL_REFERENCE_VIOLATION
+			}
+		}
+	case SQL_ERROR_NUMBER_DEADLOCK:
+		result.Kind = ERROR_SQL_DEADLOCK
+	case SQL_ERROR_NUMBER_TRUNCATION, SQL_ERROR_NUMBER_TRUNCATION_COLUMN:
+		result.Kind = ERROR_SQL_TRUNCATION
+	default:
+		if int32(number) >= SQL_ERROR_NUMBER_USER {
+			result.Kind = ERROR_SQL_USER
+			if state, err := strconv.ParseUint(result.Sql.State, 10, 8); err == nil {
+				result.Code = findSqlErrorState(uint8(state))
+			}
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+const (
+	SQL_DRIVER_MSSQL    string = "mssql"
+	SQL_DRIVER_POSTGRES string = "postgres"
+	SQL_DRIVER_MYSQL    string = "mysql"
+)
+
+// ISqlErrorNumber is implemented by the SQL Server errors, e.g. mssql.Error.
+type ISqlErrorNumber interface {
+	error
+	SQLErrorNumber() int32
+}
+
+// ISqlErrorState is implemented by the SQL Server errors, e.g. mssql.Error.
+type ISqlErrorState interface {
+	SQLErrorState() uint8
+}
+
+// ISqlErrorProcedure is implemented by the SQL Server errors, e.g. mssql.Error.
+type ISqlErrorProcedure interface {
+	SQLErrorProcName() string
+}
+
+// ISqlErrorLine is implemented by the SQL Server errors, e.g. mssql.Error.
+type ISqlErrorLine interface {
+	SQLErrorLineNo() int32
+}
+
+// ISqlErrorSqlState is implemented by the PostgreSQL errors, e.g. pgconn.PgError and pq.Error.
+type ISqlErrorSqlState interface {
+	error
+	SQLState() string
+}
+
+// SqlError is the error of a database driver, described the same way whatever the driver.
+// Its message is the message of the database, and its cause the error of the driver.
+type SqlError struct {
+	// Driver is one of the SQL_DRIVER_*, or the name of a custom extractor.
+	Driver string
+
+	// Code is the error number for SQL Server and MySQL, and the SQLSTATE for PostgreSQL.
+	Code string
+	// State is the state number for SQL Server, and the SQLSTATE for PostgreSQL and MySQL.
+	State     string
+	Line      int
+	Procedure string
+	Message   string
+
+	// Constraint, Table and Column are set when the driver reports them.
+	Constraint string
+	Table      string
+	Column     string
+
+	Err   error
+	stack []uintptr
+}
+
+func (e *SqlError) Error() string {
+	return e.Message
+}
+
+func (e *SqlError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SqlError) Format(s fmt.State, verb rune) {
+	formatError(s, verb, e)
+}
+
+func (e *SqlError) errorDetail() string {
+	result := e.Message
+	if e.Driver != "" {
+		result = fmt.Sprintf("%s %s: %s", e.Driver, e.Code, result)
+	}
+	if e.Procedure != "" || e.Line != 0 {
+		result = fmt.Sprintf("%s (procedure %s, line %d)", result, e.Procedure, e.Line)
+	}
+	return result
+}
+
+func (e *SqlError) errorStack() []uintptr {
+	return e.stack
+}
+
+// SqlErrorExtractor returns the SqlError of a driver error, or nil if the error does not come from its driver.
+type SqlErrorExtractor func(err error) *SqlError
+
+type sqlErrorExtractor struct {
+	name      string
+	extractor SqlErrorExtractor
+}
+
+var (
+	sqlErrorExtractors      []sqlErrorExtractor
+	sqlErrorExtractorsMutex sync.RWMutex
+)
+
+func init() {
+	RegisterSqlErrorExtractor("", extractAnySqlError)
+	RegisterSqlErrorExtractor(SQL_DRIVER_MYSQL, extractMysqlError)
+	RegisterSqlErrorExtractor(SQL_DRIVER_POSTGRES, extractPostgresError)
+	RegisterSqlErrorExtractor(SQL_DRIVER_MSSQL, extractMssqlError)
+}
+
+// RegisterSqlErrorExtractor registers the extractor of a driver, replacing the existing one of the same name.
+// The extractors are tried from the last registered, so a service can take precedence over the built-in ones,
+// which recognize the errors of go-mssqldb, pgx, lib/pq and go-sql-driver/mysql without importing them.
+func RegisterSqlErrorExtractor(name string, extractor SqlErrorExtractor) {
+	sqlErrorExtractorsMutex.Lock()
+	defer sqlErrorExtractorsMutex.Unlock()
+
+	for i := range sqlErrorExtractors {
+		if sqlErrorExtractors[i].name == name {
+			sqlErrorExtractors = RemoveAt(sqlErrorExtractors, i)
+			break
+		}
+	}
+
+	if extractor != nil {
+		sqlErrorExtractors = append(sqlErrorExtractors, sqlErrorExtractor{name: name, extractor: extractor})
+	}
+}
+
+// ExtractSqlError returns the SqlError of an error, found through its wrappers,
+// or nil if no extractor recognizes it.
+func ExtractSqlError(err error) *SqlError {
+	if err == nil {
+		return nil
+	}
+
+	var result *SqlError
+	if errors.As(err, &result) {
+		return result
+	}
+
+	sqlErrorExtractorsMutex.RLock()
+	extractors := make([]sqlErrorExtractor, len(sqlErrorExtractors))
+	copy(extractors, sqlErrorExtractors)
+	sqlErrorExtractorsMutex.RUnlock()
+
+	for i := len(extractors) - 1; i >= 0; i-- {
+		if result = extractors[i].extractor(err); result != nil {
+			if result.Driver == "" {
+				result.Driver = extractors[i].name
+			}
+			if result.Err == nil {
+				result.Err = err
+			}
+			result.stack = callers()
+			return result
+		}
+	}
+
+	return nil
+}
+
+// extractMssqlError recognizes the errors of go-mssqldb by their methods.
+func extractMssqlError(err error) *SqlError {
+	var e ISqlErrorNumber
+	if !errors.As(err, &e) {
+		return nil
+	}
+
+	result := &SqlError{Code: strconv.FormatInt(int64(e.SQLErrorNumber()), 10), Message: err.Error(), Err: e}
+	if v, ok := e.(ISqlError); ok {
+		result.Message = v.SQLErrorMessage()
+	}
+	if v, ok := e.(ISqlErrorState); ok {
+		result.State = strconv.Itoa(int(v.SQLErrorState()))
+	}
+	if v, ok := e.(ISqlErrorProcedure); ok {
+		result.Procedure = v.SQLErrorProcName()
+	}
+	if v, ok := e.(ISqlErrorLine); ok {
+		result.Line = int(v.SQLErrorLineNo())
+	}
+	return result
+}
+
+// extractPostgresError recognizes the errors of pgx and lib/pq by their SQLState method, and reads their fields.
+func extractPostgresError(err error) *SqlError {
+	var e ISqlErrorSqlState
+	if !errors.As(err, &e) {
+		return nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(e))
+	result := &SqlError{
+		Code:       e.SQLState(),
+		State:      e.SQLState(),
+		Err:        e,
+		Message:    reflectStringField(value, "Message"),
+		Procedure:  reflectStringField(value, "Where"),
+		Constraint: reflectStringField(value, "ConstraintName", "Constraint"),
+		Table:      reflectStringField(value, "TableName", "Table"),
+		Column:     reflectStringField(value, "ColumnName", "Column"),
+	}
+	if result.Message == "" {
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// extractMysqlError recognizes the MySQLError of go-sql-driver/mysql by its name and fields.
+func extractMysqlError(err error) *SqlError {
+	found := findError(err, func(e error) bool {
+		value := reflect.Indirect(reflect.ValueOf(e))
+		return value.Kind() == reflect.Struct && value.Type().Name() == "MySQLError"
+	})
+	if found == nil {
+		return nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(found))
+	result := &SqlError{Message: reflectStringField(value, "Message"), Err: found}
+	if number := value.FieldByName("Number"); number.IsValid() && number.CanUint() {
+		result.Code = strconv.FormatUint(number.Uint(), 10)
+	}
+	if state := value.FieldByName("SQLState"); state.IsValid() && state.Kind() == reflect.Array {
+		bytes := make([]byte, state.Len())
+		reflect.Copy(reflect.ValueOf(bytes), state)
+		result.State = string(bytes)
+	}
+	if result.Message == "" {
+		result.Message = found.Error()
+	}
+	return result
+}
+
+// extractAnySqlError recognizes the other ISqlError, e.g. the type given to SetType before the extractors existed.
+func extractAnySqlError(err error) *SqlError {
+	var e ISqlError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	return &SqlError{Message: e.SQLErrorMessage(), Err: err}
+}
+
+// findError returns the first error of the chain matching the predicate, following every cause.
+func findError(err error, predicate func(error) bool) error {
+	if err == nil {
+		return nil
+	}
+	if predicate(err) {
+		return err
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return findError(e.Unwrap(), predicate)
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			if result := findError(cause, predicate); result != nil {
+				return result
+			}
+		}
+	}
+	return nil
+}
+
+// reflectStringField returns the first string field of a struct found among the names, or an empty string.
+func reflectStringField(value reflect.Value, names ...string) string {
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for _, e := range names {
+		if field := value.FieldByName(e); field.IsValid() && field.Kind() == reflect.String && field.String() != "" {
+			return field.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// MySQLError mirrors the error of go-sql-driver/mysql, recognized by its type name and fields.
+type MySQLError struct {
+	Number   uint16
+	SQLState [5]byte
+	Message  string
+}
+
+func (e *MySQLError) Error() string {
+	return fmt.Sprintf("Error %d (%s): %s", e.Number, e.SQLState, e.Message)
+}
+
+// testPgconnError mirrors pgconn.PgError of pgx.
+type testPgconnError struct {
+	Severity       string
+	Code           string
+	Message        string
+	Detail         string
+	Where          string
+	TableName      string
+	ColumnName     string
+	ConstraintName string
+}
+
+func (e *testPgconnError) Error() string {
+	return e.Severity + ": " + e.Message + " (SQLSTATE " + e.Code + ")"
+}
+
+func (e *testPgconnError) SQLState() string {
+	return e.Code
+}
+
+// testPqErrorCode mirrors pq.ErrorCode, a string type.
+type testPqErrorCode string
+
+// testPqError mirrors pq.Error of lib/pq.
+type testPqError struct {
+	Severity   string
+	Code       testPqErrorCode
+	Message    string
+	Detail     string
+	Where      string
+	Table      string
+	Column     string
+	Constraint string
+}
+
+func (e *testPqError) Error() string {
+	return "pq: " + e.Message
+}
+
+func (e *testPqError) SQLState() string {
+	return string(e.Code)
+}
+
+// testMssqlError mirrors mssql.Error of go-mssqldb.
+type testMssqlError struct {
+	Number   int32
+	State    uint8
+	Message  string
+	ProcName string
+	LineNo   int32
+}
+
+func (e testMssqlError) Error() string {
+	return "mssql: " + e.Message
+}
+
+func (e testMssqlError) SQLErrorNumber() int32 {
+	return e.Number
+}
+
+func (e testMssqlError) SQLErrorState() uint8 {
+	return e.State
+}
+
+func (e testMssqlError) SQLErrorProcName() string {
+	return e.ProcName
+}
+
+func (e testMssqlError) SQLErrorLineNo() int32 {
+	return e.LineNo
+}
+
+func (e testMssqlError) SQLErrorMessage() string {
+	return e.Message
+}
+
+func TestExtractSqlError(t *testing.T) {
+	mysqlErr := &MySQLError{Number: 1062, SQLState: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry 'A-1' for key 'orders.number'"}
+	mysqlErrWithoutMessage := &MySQLError{Number: 1213}
+	pgconnErr := &testPgconnError{Severity: "ERROR", Code: "23505", Message: `duplicate key value violates unique constraint "orders_number_key"`,
+		Where: "PL/pgSQL function save_order()", TableName: "orders", ColumnName: "number", ConstraintName: "orders_number_key"}
+	pqErr := &testPqError{Severity: "ERROR", Code: "23503", Message: `insert or update on table "orders" violates foreign key constraint "orders_customer_id_fkey"`,
+		Table: "orders", Constraint: "orders_customer_id_fkey"}
+	mssqlErr := testMssqlError{Number: 50000, State: 12, Message: "The order is locked.", ProcName: "SaveOrder", LineNo: 7}
+
+	tests := []struct {
+		name     string
+		err      error
+		cause    error
+		expected SqlError
+	}{
+		{"mysql", mysqlErr, mysqlErr,
+			SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1062", State: "23000", Message: "Duplicate entry 'A-1' for key 'orders.number'"}},
+		{"wrapped mysql", fmt.Errorf("save order: %w", mysqlErr), mysqlErr,
+			SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1062", State: "23000", Message: "Duplicate entry 'A-1' for key 'orders.number'"}},
+		{"joined mysql", errors.Join(errors.New("rollback failed"), fmt.Errorf("save order: %w", mysqlErr)), mysqlErr,
+			SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1062", State: "23000", Message: "Duplicate entry 'A-1' for key 'orders.number'"}},
+		{"mysql without message", mysqlErrWithoutMessage, mysqlErrWithoutMessage,
+			SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1213", State: "\x00\x00\x00\x00\x00", Message: mysqlErrWithoutMessage.Error()}},
+		{"pgx", fmt.Errorf("save order: %w", pgconnErr), pgconnErr,
+			SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23505", State: "23505", Message: pgconnErr.Message, Procedure: "PL/pgSQL function save_order()",
+				Constraint: "orders_number_key", Table: "orders", Column: "number"}},
+		{"lib/pq", pqErr, pqErr,
+			SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23503", State: "23503", Message: pqErr.Message, Constraint: "orders_customer_id_fkey", Table: "orders"}},
+		{"go-mssqldb", fmt.Errorf("save order: %w", mssqlErr), mssqlErr,
+			SqlError{Driver: SQL_DRIVER_MSSQL, Code: "50000", State: "12", Message: "The order is locked.", Procedure: "SaveOrder", Line: 7}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := ExtractSqlError(test.err)
+			if result == nil {
+				t.Fatal("error is not extracted")
+			}
+			if result.Err != test.cause {
+				t.Errorf("cause is %#v, expected %#v", result.Err, test.cause)
+			}
+
+			result.Err, result.stack = nil, nil
+			if !reflect.DeepEqual(*result, test.expected) {
+				t.Errorf("error is %+v, expected %+v", *result, test.expected)
+			}
+		})
+	}
+
+	if result := extractMysqlError(errors.New("Error 1062: Duplicate entry")); result != nil {
+		t.Errorf("error without MySQLError is extracted as %+v", result)
+	}
+	if result := ExtractSqlError(errors.New("connection refused")); result != nil {
+		t.Errorf("error without SQL error is extracted as %+v", result)
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testPgError mirrors the Detail field of the PostgreSQL driver errors, read by ClassifySqlError.
+type testPgError struct {
+	Detail string
+}
+
+func (e *testPgError) Error() string {
+	return e.Detail
+}
+
+func TestClassifySqlError(t *testing.T) {
+	sqlErrorStatesMutex.Lock()
+	states := sqlErrorStates
+	sqlErrorStatesMutex.Unlock()
+	t.Cleanup(func() {
+		sqlErrorStatesMutex.Lock()
+		sqlErrorStates = states
+		sqlErrorStatesMutex.Unlock()
+	})
+	RegisterSqlErrorStates(10, 19, "test_order_closed")
+	RegisterSqlErrorStates(12, 12, "test_order_locked")
+
+	tests := []struct {
+		name       string
+		err        *SqlError
+		kind       error
+		code       string
+		constraint string
+		table      string
+		column     string
+		value      string
+	}{
+		{"mssql unique constraint", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "2627",
+			Message: "Violation of UNIQUE KEY constraint 'UQ_Orders_Number'. Cannot insert duplicate key in object 'dbo.Orders'. The duplicate key value is (A-1)."},
+			ERROR_SQL_UNIQUE_VIOLATION, "", "UQ_Orders_Number", "dbo.Orders", "", "A-1"},
+		{"mssql unique index", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "2601",
+			Message: "Cannot insert duplicate key row in object 'dbo.Orders' with unique index 'IX_Orders_Number'. The duplicate key value is (A-1)."},
+			ERROR_SQL_UNIQUE_VIOLATION, "", "IX_Orders_Number", "dbo.Orders", "", "A-1"},
+		{"mssql foreign key", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "547",
+			Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_Orders_Customers". The conflict occurred in database "Shop", table "dbo.Customers", column 'Id'.`},
+			ERROR_SQL_FOREIGN_KEY_VIOLATION, "", "FK_Orders_Customers", "dbo.Customers", "Id", ""},
+		{"mssql reference", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "547",
+			Message: `The DELETE statement conflicted with the REFERENCE constraint "FK_Orders_Customers". The conflict occurred in database "Shop", table "dbo.Orders", column 'CustomerId'.`},
+			ERROR_SQL_REFERENCE_VIOLATION, "", "FK_Orders_Customers", "dbo.Orders", "CustomerId", ""},
+		{"mssql check", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "547",
+			Message: `The INSERT statement conflicted with the CHECK constraint "CK_Orders_Amount". The conflict occurred in database "Shop", table "dbo.Orders", column 'Amount'.`},
+			ERROR_SQL_CHECK_VIOLATION, "", "CK_Orders_Amount", "dbo.Orders", "Amount", ""},
+		{"mssql deadlock", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "1205",
+			Message: "Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim. Rerun the transaction."},
+			ERROR_SQL_DEADLOCK, "", "", "", "", ""},
+		{"mssql truncation", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "2628",
+			Message: "String or binary data would be truncated in table 'Shop.dbo.Orders', column 'Note'. Truncated value: 'abc'."},
+			ERROR_SQL_TRUNCATION, "", "", "Shop.dbo.Orders", "Note", ""},
+		{"mssql user error", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "50000", State: "1", Message: "The order is invalid."},
+			ERROR_SQL_USER, "", "", "", "", ""},
+		{"mssql user error of a state range", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "50000", State: "15", Message: "The order is closed."},
+			ERROR_SQL_USER, "test_order_closed", "", "", "", ""},
+		{"mssql user error of the narrowest state range", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "50001", State: "12", Message: "The order is locked."},
+			ERROR_SQL_USER, "test_order_locked", "", "", "", ""},
+		{"mssql unclassified", &SqlError{Driver: SQL_DRIVER_MSSQL, Code: "208", Message: "Invalid object name 'dbo.Order'."},
+			nil, "", "", "", "", ""},
+
+		{"postgres unique", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23505", Constraint: "orders_number_key", Table: "orders",
+			Message: `duplicate key value violates unique constraint "orders_number_key"`, Err: &testPgError{Detail: "Key (number)=(A-1) already exists."}},
+			ERROR_SQL_UNIQUE_VIOLATION, "", "orders_number_key", "orders", "", "A-1"},
+		{"postgres foreign key", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23503", Constraint: "orders_customer_id_fkey",
+			Message: `insert or update on table "orders" violates foreign key constraint "orders_customer_id_fkey"`},
+			ERROR_SQL_FOREIGN_KEY_VIOLATION, "", "orders_customer_id_fkey", "orders", "", ""},
+		{"postgres reference", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23503", Constraint: "orders_customer_id_fkey",
+			Message: `update or delete on table "customers" violates foreign key constraint "orders_customer_id_fkey" on table "orders"`},
+			ERROR_SQL_REFERENCE_VIOLATION, "", "orders_customer_id_fkey", "customers", "", ""},
+		{"postgres check", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "23514", Constraint: "orders_amount_check", Table: "orders",
+			Message: `new row for relation "orders" violates check constraint "orders_amount_check"`},
+			ERROR_SQL_CHECK_VIOLATION, "", "orders_amount_check", "orders", "", ""},
+		{"postgres deadlock", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "40P01", Message: "deadlock detected"},
+			ERROR_SQL_DEADLOCK, "", "", "", "", ""},
+		{"postgres truncation", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "22001", Message: "value too long for type character varying(10)"},
+			ERROR_SQL_TRUNCATION, "", "", "", "", ""},
+		{"postgres user error", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "P0001", Message: "The order is closed."},
+			ERROR_SQL_USER, "", "", "", "", ""},
+		{"postgres unclassified", &SqlError{Driver: SQL_DRIVER_POSTGRES, Code: "42P01", Message: `relation "order" does not exist`},
+			nil, "", "", "", "", ""},
+
+		{"mysql unique", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1062", Message: "Duplicate entry 'A-1' for key 'orders.number'"},
+			ERROR_SQL_UNIQUE_VIOLATION, "", "orders.number", "", "", "A-1"},
+		{"mysql foreign key", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1452",
+			Message: "Cannot add or update a child row: a foreign key constraint fails (`shop`.`orders`, CONSTRAINT `fk_orders_customers` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`))"},
+			ERROR_SQL_FOREIGN_KEY_VIOLATION, "", "fk_orders_customers", "", "", ""},
+		{"mysql reference", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1451",
+			Message: "Cannot delete or update a parent row: a foreign key constraint fails (`shop`.`orders`, CONSTRAINT `fk_orders_customers` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`id`))"},
+			ERROR_SQL_REFERENCE_VIOLATION, "", "fk_orders_customers", "", "", ""},
+		{"mysql check", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "3819", Message: "Check constraint 'chk_amount' is violated."},
+			ERROR_SQL_CHECK_VIOLATION, "", "chk_amount", "", "", ""},
+		{"mysql deadlock", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1213", Message: "Deadlock found when trying to get lock; try restarting transaction"},
+			ERROR_SQL_DEADLOCK, "", "", "", "", ""},
+		{"mysql truncation", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1406", Message: "Data too long for column 'note' at row 1"},
+			ERROR_SQL_TRUNCATION, "", "", "", "note", ""},
+		{"mysql user error", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1644", Message: "The order is closed."},
+			ERROR_SQL_USER, "", "", "", "", ""},
+		{"mysql unclassified", &SqlError{Driver: SQL_DRIVER_MYSQL, Code: "1146", Message: "Table 'shop.order' doesn't exist"},
+			nil, "", "", "", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := ClassifySqlError(fmt.Errorf("query: %w", test.err))
+			if test.kind == nil {
+				if result != nil {
+					t.Errorf("error is classified as %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("error is not classified")
+			}
+
+			code := IIF(test.code == "", test.kind.Error(), test.code)
+			if result.Kind != test.kind || result.Code != code || result.Constraint != test.constraint ||
+				result.Table != test.table || result.Column != test.column || result.Value != test.value {
+				t.Errorf("error is classified as %+v", result)
+			}
+			if !errors.Is(result, test.kind) || !errors.Is(result, test.err) || ClassifySqlError(result) != result {
+				t.Errorf("error %+v does not match its kind and its SQL error", result)
+			}
+		})
+	}
+}